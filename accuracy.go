@@ -0,0 +1,23 @@
+package clockwork
+
+import (
+	"time"
+)
+
+// MeasureTimerAccuracy starts samples timers of duration d on clock, waits for
+// all of them and returns, for each, how late it fired: the time it delivered
+// minus the time it was due. With the real clock this shows the jitter of the
+// platform's timers; with a FakeClock, which is exact, every overshoot is zero.
+func MeasureTimerAccuracy(clock Clock, d time.Duration, samples int) []time.Duration {
+	due := make([]time.Time, samples)
+	timers := make([]Timer, samples)
+	for i := range timers {
+		due[i] = clock.Now().Add(d)
+		timers[i] = clock.NewTimer(d)
+	}
+	overshoots := make([]time.Duration, samples)
+	for i, t := range timers {
+		overshoots[i] = (<-t.C()).Sub(due[i])
+	}
+	return overshoots
+}
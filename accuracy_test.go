@@ -0,0 +1,33 @@
+package clockwork
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMeasureTimerAccuracyFakeClock(t *testing.T) {
+	fc := NewFakeClock()
+	result := make(chan []time.Duration)
+	go func() {
+		result <- MeasureTimerAccuracy(fc, time.Second, 5)
+	}()
+	fc.BlockUntil(5)
+	fc.Advance(time.Second)
+	overshoots := <-result
+	if len(overshoots) != 5 {
+		t.Fatalf("got %d samples, want %d", len(overshoots), 5)
+	}
+	for i, o := range overshoots {
+		if o != 0 {
+			t.Errorf("sample %d overshot by %v, want 0", i, o)
+		}
+	}
+}
+
+func TestMeasureTimerAccuracyRealClock(t *testing.T) {
+	for i, o := range MeasureTimerAccuracy(NewRealClock(), time.Millisecond, 3) {
+		if o < 0 {
+			t.Errorf("sample %d fired %v early", i, -o)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package clockwork
+
+import "time"
+
+// ClockCapabilities describes a Clock, for generic code which needs to adapt
+// to the implementation it is given, e.g. to skip a test which requires a
+// FakeClock. Fields may be added over time; their zero value is always a
+// conservative answer.
+type ClockCapabilities struct {
+	// Fake tells the clock only moves when advanced, as a FakeClock does
+	Fake bool
+	// Resolution is the smallest difference between two times the clock
+	// can represent. The actual granularity of a real clock depends on the
+	// platform and is usually coarser.
+	Resolution time.Duration
+	// Location is the location of the times the clock returns
+	Location *time.Location
+	// Features are the optional features the clock supports
+	Features Feature
+}
+
+// Feature is an optional feature of a Clock, see ClockCapabilities. Features
+// combine as a bit set.
+type Feature uint
+
+const (
+	// FeatureAdvance tells the clock is a FakeClock, which can be advanced
+	// and whose waiters can be inspected
+	FeatureAdvance Feature = 1 << iota
+	// FeatureTickerStats tells the Period and Dropped methods of the tickers
+	// report actual values rather than zero
+	FeatureTickerStats
+	// FeatureUnderlyingTimer tells the T method of the timers returns the
+	// underlying *time.Timer rather than nil
+	FeatureUnderlyingTimer
+)
+
+// Has reports whether f holds every feature of want.
+func (f Feature) Has(want Feature) bool {
+	return f&want == want
+}
+
+// Capabilities describes the realClock: not fake, with the nanosecond
+// resolution of the time package, in its location or the local one, and
+// timers backed by the time package.
+func (rc *realClock) Capabilities() ClockCapabilities {
+	loc := rc.loc
+	if loc == nil {
+		loc = time.Local
+	}
+	return ClockCapabilities{
+		Resolution: time.Nanosecond,
+		Location:   loc,
+		Features:   FeatureUnderlyingTimer,
+	}
+}
+
+// Capabilities describes the fakeClock: fake, with a nanosecond resolution,
+// in the location of its current time, and tickers tracking their period and
+// dropped ticks.
+func (fc *fakeClock) Capabilities() ClockCapabilities {
+	return ClockCapabilities{
+		Fake:       true,
+		Resolution: time.Nanosecond,
+		Location:   fc.Now().Location(),
+		Features:   FeatureAdvance | FeatureTickerStats,
+	}
+}
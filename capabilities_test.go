@@ -0,0 +1,50 @@
+package clockwork
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClockCapabilities(t *testing.T) {
+	loc := time.FixedZone("local", -3600)
+	for _, tc := range []struct {
+		name  string
+		clock Clock
+		want  ClockCapabilities
+	}{
+		{"real", NewRealClock(), ClockCapabilities{Resolution: time.Nanosecond, Location: time.Local, Features: FeatureUnderlyingTimer}},
+		{"real in location", NewRealClockInLocation(loc), ClockCapabilities{Resolution: time.Nanosecond, Location: loc, Features: FeatureUnderlyingTimer}},
+		{"fake", NewFakeClock(), ClockCapabilities{Fake: true, Resolution: time.Nanosecond, Location: time.UTC, Features: FeatureAdvance | FeatureTickerStats}},
+		{"fake in location", NewFakeClock(WithLocation(loc)), ClockCapabilities{Fake: true, Resolution: time.Nanosecond, Location: loc, Features: FeatureAdvance | FeatureTickerStats}},
+		{"scaled fake", NewScaledClock(NewFakeClock(), 2), ClockCapabilities{Resolution: time.Nanosecond, Location: time.UTC, Features: FeatureTickerStats}},
+		{"scaled real", NewScaledClock(NewRealClock(), 2), ClockCapabilities{Resolution: time.Nanosecond, Location: time.Local, Features: FeatureUnderlyingTimer | FeatureTickerStats}},
+	} {
+		if got := tc.clock.Capabilities(); got != tc.want {
+			t.Errorf("%s: got %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestClockCapabilitiesFeatures(t *testing.T) {
+	// The advertised features match what the clocks do.
+	rc := NewRealClock()
+	timer := rc.NewTimer(time.Hour)
+	defer timer.Stop()
+	if got := rc.Capabilities().Features.Has(FeatureUnderlyingTimer); got != (timer.T() != nil) {
+		t.Errorf("real clock: FeatureUnderlyingTimer is %v, T returned %v", got, timer.T())
+	}
+
+	var fc Clock = NewFakeClock()
+	features := fc.Capabilities().Features
+	if _, ok := fc.(FakeClock); features.Has(FeatureAdvance) != ok {
+		t.Errorf("fake clock: FeatureAdvance is %v, FakeClock implemented: %v", features.Has(FeatureAdvance), ok)
+	}
+	ticker := fc.NewTicker(time.Second)
+	defer ticker.Stop()
+	if got := features.Has(FeatureTickerStats); got != (ticker.Period() == time.Second) {
+		t.Errorf("fake clock: FeatureTickerStats is %v, Period returned %v", got, ticker.Period())
+	}
+	if features.Has(FeatureAdvance | FeatureUnderlyingTimer) {
+		t.Error("fake clock has FeatureUnderlyingTimer")
+	}
+}
@@ -0,0 +1,41 @@
+package clockwork
+
+import "time"
+
+// ClockChain composes the clock decorators of the package over a base clock,
+// see Wrap. It is a value: each With method returns a new chain, so a chain
+// can be shared as the starting point of several others.
+type ClockChain struct {
+	clock Clock
+}
+
+// Wrap starts a ClockChain over base, e.g.
+// Wrap(base).WithOffset(time.Hour).WithScale(10).WithCounting().Build(). Each
+// With method wraps the clock built so far, so the decorators apply in the
+// order they are added, the last one being the outermost.
+func Wrap(base Clock) ClockChain {
+	return ClockChain{clock: base}
+}
+
+// WithOffset wraps the clock of the chain with NewOffsetClock.
+func (cc ClockChain) WithOffset(offset time.Duration) ClockChain {
+	return ClockChain{clock: NewOffsetClock(cc.clock, offset)}
+}
+
+// WithScale wraps the clock of the chain with NewScaledClock, which panics if
+// factor isn't positive.
+func (cc ClockChain) WithScale(factor float64) ClockChain {
+	return ClockChain{clock: NewScaledClock(cc.clock, factor)}
+}
+
+// WithCounting wraps the clock of the chain with NewCountingClock. The calls
+// are read from the built clock, asserted to a CountingClock.
+func (cc ClockChain) WithCounting() ClockChain {
+	return ClockChain{clock: NewCountingClock(cc.clock)}
+}
+
+// Build returns the composed clock, the base clock itself if no decorator
+// was added.
+func (cc ClockChain) Build() Clock {
+	return cc.clock
+}
@@ -0,0 +1,34 @@
+package clockwork
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClockChain(t *testing.T) {
+	fc := NewFakeClock()
+	if c := Wrap(fc).Build(); c != fc {
+		t.Fatalf("empty chain built %v, want the base clock", c)
+	}
+
+	start := fc.Now()
+	c := Wrap(fc).WithOffset(time.Minute).WithScale(2).WithCounting().Build()
+	if want := start.Add(time.Minute); !c.Now().Equal(want) {
+		t.Fatalf("got %v, want %v", c.Now(), want)
+	}
+	timer := c.NewTimer(2 * time.Second)
+	fc.Advance(time.Second)
+	if want := start.Add(time.Minute + 2*time.Second); !c.Now().Equal(want) {
+		t.Errorf("got %v after advancing the base clock, want %v", c.Now(), want)
+	}
+	// The scaled timer is an AfterFunc of the base clock, firing in its own
+	// goroutine.
+	select {
+	case <-timer.C():
+	case <-time.After(time.Second):
+		t.Error("timer of the chain did not fire with the base clock")
+	}
+	if got := c.(CountingClock).Calls("Now"); got != 2 {
+		t.Errorf("got %d calls to Now, want 2", got)
+	}
+}
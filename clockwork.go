@@ -1,6 +1,17 @@
 package clockwork
 
 import (
+	"container/heap"
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"math/rand"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -18,6 +29,32 @@ type Clock interface {
 	NewTimer(d time.Duration) Timer
 	AfterFunc(d time.Duration, f func()) Timer
 	Location() *time.Location
+	// TruncateToWindow returns the start of the window of the given size
+	// which contains t, windows being aligned on the origin of the clock
+	TruncateToWindow(t time.Time, window time.Duration) time.Time
+	// Parse parses a formatted time like time.Parse, but in the location of
+	// the clock rather than UTC when value carries no time zone
+	Parse(layout, value string) (time.Time, error)
+	// Capabilities describes the implementation of the clock
+	Capabilities() ClockCapabilities
+	// SleepContext is like Sleep but returns the context's error early if
+	// the context is done first
+	SleepContext(ctx context.Context, d time.Duration) error
+	// AtLeast calls f, then sleeps for whatever is left for the whole to
+	// take at least d
+	AtLeast(d time.Duration, f func())
+	// TimerContext returns a Timer and a context derived from parent which
+	// expire together after d
+	TimerContext(parent context.Context, d time.Duration) (Timer, context.Context, context.CancelFunc)
+	// NewTickerFunc returns a Ticker calling f in its own goroutine on every
+	// tick instead of sending on its channel
+	NewTickerFunc(d time.Duration, f func()) Ticker
+	// NewTimerContext is like NewTimer, but the timer is stopped when the
+	// context is done
+	NewTimerContext(ctx context.Context, d time.Duration) Timer
+	// Tick is like time.Tick: it returns the channel of a ticker which is
+	// never stopped
+	Tick(d time.Duration) <-chan time.Time
 }
 
 // Timer provides an interface to a time.Timer which is testable.
@@ -49,6 +86,60 @@ func (rt *realTimer) Stop() bool {
 	return rt.t.Stop()
 }
 
+// NewTimerContext creates a Timer like NewTimer, which is stopped by a
+// goroutine watching the context as soon as it is done. See the fakeClock
+// version for the interaction with Stop and Reset.
+func (rc *realClock) NewTimerContext(ctx context.Context, d time.Duration) Timer {
+	t := &realContextTimer{
+		c:        make(chan time.Time, 1),
+		released: make(chan struct{}),
+	}
+	t.t = time.AfterFunc(d, func() {
+		select {
+		case t.c <- time.Now():
+		default:
+		}
+		t.release()
+	})
+	if ctx.Done() != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				t.Stop()
+			case <-t.released:
+			}
+		}()
+	}
+	return t
+}
+
+// realContextTimer is a real timer from NewTimerContext. It runs as an
+// AfterFunc timer so that it knows when it fires.
+type realContextTimer struct {
+	t        *time.Timer
+	c        chan time.Time
+	released chan struct{}
+	once     sync.Once
+}
+
+func (rt *realContextTimer) C() <-chan time.Time { return rt.c }
+func (rt *realContextTimer) T() *time.Timer      { return rt.t }
+func (rt *realContextTimer) Reset(d time.Duration) bool {
+	return rt.t.Reset(d)
+}
+func (rt *realContextTimer) Stop() bool {
+	stopped := rt.t.Stop()
+	if stopped {
+		rt.release()
+	}
+	return stopped
+}
+
+// release stops the watching of the context.
+func (rt *realContextTimer) release() {
+	rt.once.Do(func() { close(rt.released) })
+}
+
 // FakeClock provides an interface for a clock which can be
 // manually advanced through time
 type FakeClock interface {
@@ -59,36 +150,252 @@ type FakeClock interface {
 	// BlockUntil will block until the FakeClock has the given number of
 	// sleepers (callers of Sleep or After)
 	BlockUntil(n int)
+	// NowBytes appends the current time of the FakeClock, formatted as
+	// RFC3339Nano, to buf and returns the extended buffer
+	NowBytes(buf []byte) []byte
+	// NewUnarmedTimer creates a Timer bound to the FakeClock which is not
+	// scheduled until Reset is called on it
+	NewUnarmedTimer() Timer
+	// ReplayTimeline advances the FakeClock to each of the given instants in
+	// order, notifying sleepers along the way
+	ReplayTimeline(times []time.Time) error
+	// State returns the current time of the FakeClock along with the earliest
+	// expiration of its sleepers, if any, as a single consistent snapshot
+	State() (now time.Time, next time.Time, hasNext bool)
+	// SetMaxTicksPerAdvance stops the tickers with more than n ticks due in
+	// one advance of the FakeClock, after n ticks; zero means unlimited
+	SetMaxTicksPerAdvance(n int)
+	// Errors returns the problems recorded by the FakeClock's safety checks
+	Errors() []error
+	// WaitNextTick blocks until any ticker of the FakeClock fires, or the
+	// context is done
+	WaitNextTick(ctx context.Context) error
+	// EnableLeakFinalizers makes the FakeClock record an error whenever a
+	// ticker it created is garbage collected without having been stopped
+	EnableLeakFinalizers()
+	// RealTimeInAdvance returns the wall-clock time spent advancing the
+	// FakeClock so far
+	RealTimeInAdvance() time.Duration
+	// WouldFire reports whether advancing the FakeClock by d would notify
+	// any sleeper
+	WouldFire(d time.Duration) bool
+	// StopWhere stops every sleeper for which pred returns true and returns
+	// how many were stopped
+	StopWhere(pred func(WaiterInfo) bool) int
+	// PublishExpvar publishes the current time and number of sleepers of the
+	// FakeClock as an expvar with the given name
+	PublishExpvar(name string)
+	// FireOnly moves the FakeClock to the expiration of the given timer and
+	// fires it, leaving every other sleeper pending
+	FireOnly(t Timer) error
+	// SetHerdBatchSize makes an advance of the FakeClock notify its expired
+	// sleepers in batches of n, releasing the clock between batches
+	SetHerdBatchSize(n int)
+	// Done returns a channel which is closed once the FakeClock reaches t
+	Done(t time.Time) <-chan struct{}
+	// HasBlockers reports whether any caller of BlockUntil,
+	// BlockUntilSleepers or BlockUntilAtMost is still waiting
+	HasBlockers() bool
+	// AdvanceToMedian advances the FakeClock to the median expiration of its
+	// sleepers and returns how many sleepers it notified
+	AdvanceToMedian() int
+	// RecordFires writes a line to w for every sleeper the FakeClock
+	// notifies, see CompareFires
+	RecordFires(w io.Writer)
+	// AdvanceChecked advances the FakeClock like Advance, then returns an
+	// error if it left callers of BlockUntil, BlockUntilSleepers or
+	// BlockUntilAtMost waiting with no sleeper pending
+	AdvanceChecked(d time.Duration) error
+	// MonotonicSince returns the time elapsed since start on the monotonic
+	// timeline of the FakeClock, which only moves forward
+	MonotonicSince(start time.Time) time.Duration
+	// Dimension returns the FakeClock managing the named, independent time
+	// axis of this FakeClock
+	Dimension(name string) FakeClock
+	// AdvanceDimension advances the named time axis of the FakeClock
+	AdvanceDimension(name string, d time.Duration)
+	// NewTimerInDimension creates a Timer which fires when the named time
+	// axis of the FakeClock advances past d
+	NewTimerInDimension(name string, d time.Duration) Timer
+	// SetDeliverySink installs a function which sees every sleeper expiring
+	// during an advance and may suppress its usual notification
+	SetDeliverySink(sink func(e WaiterInfo, at time.Time) bool)
+	// DriveUntilQuiescent keeps advancing the FakeClock to its next
+	// expiration until no sleeper is left or the context is done
+	DriveUntilQuiescent(ctx context.Context) error
+	// TrackGoroutineReads turns on or off the counting of Now calls per
+	// goroutine, see PerGoroutineReads
+	TrackGoroutineReads(enabled bool)
+	// PerGoroutineReads returns the number of Now calls made by each
+	// goroutine, by goroutine id, while tracking was on
+	PerGoroutineReads() map[int64]int
+	// SetDilation makes every Advance(d) of the FakeClock move it by d
+	// scaled by factor of the time remaining until target
+	SetDilation(target time.Time, factor func(remaining time.Duration) float64)
+	// ResetAll resets each of the given timers of the FakeClock to its new
+	// duration at once, so that no advance sees only some of them reset
+	ResetAll(updates map[Timer]time.Duration) error
+	// TimeToDrain returns how far the FakeClock must advance for all its
+	// pending sleepers but the tickers to fire
+	TimeToDrain() time.Duration
+	// Waiters returns a description of each pending sleeper of the
+	// FakeClock, by order of expiration
+	Waiters() []WaiterInfo
+	// DiffTimeline compares the pending sleepers of the FakeClock with the
+	// ones of other and describes each difference
+	DiffTimeline(other FakeClock) []string
+	// IdleChan returns a channel which is closed once the FakeClock has no
+	// pending sleeper
+	IdleChan() <-chan struct{}
+	// RegisterMetrics registers functions the FakeClock calls with its
+	// current time and number of sleepers whenever they change
+	RegisterMetrics(setTime func(float64), setWaiters func(float64))
+	// FiresBeforeTimer reports whether timer a is due to fire strictly
+	// before timer b, both of which must be waiting on the FakeClock
+	FiresBeforeTimer(a, b Timer) (bool, error)
+	// SetYieldAfterFire makes an advance of the FakeClock yield the
+	// processor after notifying each sleeper
+	SetYieldAfterFire(enabled bool)
+	// SetMaxWaiters makes the FakeClock panic as soon as more than n
+	// sleepers are pending; zero means unlimited
+	SetMaxWaiters(n int)
+	// PeekFire returns the time a fired timer of the FakeClock delivered on
+	// its channel, leaving it there to be received
+	PeekFire(t Timer) (time.Time, bool)
+	// BlockUntilSleepers blocks until the FakeClock has exactly n callers of
+	// Sleep waiting, or the context is done
+	BlockUntilSleepers(ctx context.Context, n int) error
+	// AdvanceRandom advances the FakeClock by a duration drawn in [min, max]
+	// from a generator seeded with seed, and returns that duration
+	AdvanceRandom(min, max time.Duration, seed int64) time.Duration
+	// SetFireBias makes advances of the FakeClock notify sleepers later, or
+	// earlier for a negative bias, than their expiration
+	SetFireBias(bias time.Duration)
+	// AdvanceTo advances the FakeClock to the absolute time t, notifying
+	// sleepers like Advance
+	AdvanceTo(t time.Time)
+	// SetTime sets the current time of the FakeClock without notifying any
+	// sleeper
+	SetTime(t time.Time)
+	// SetAutoAdvance makes the FakeClock advance on its own to a sleeper
+	// scheduled at most step ahead when no other sleeper is pending; zero
+	// turns it off
+	SetAutoAdvance(step time.Duration)
+	// AfterFuncNamed is like AfterFunc, attaching name to the timer for
+	// PendingAfterFuncs to list
+	AfterFuncNamed(d time.Duration, name string, f func()) Timer
+	// PendingAfterFuncs returns the names of the AfterFunc timers of the
+	// FakeClock which have yet to fire
+	PendingAfterFuncs() []string
+	// NewTimerDedup is like NewTimer, but resets the pending timer created
+	// with the same key instead of creating another one
+	NewTimerDedup(key string, d time.Duration) Timer
+	// WaiterCount returns the number of sleepers of the FakeClock
+	WaiterCount() int
+	// GraphvizTimeline describes the pending sleepers of the FakeClock
+	// along its time axis as a Graphviz DOT graph
+	GraphvizTimeline() string
+	// AdvanceToNextTimer advances the FakeClock to its earliest expiration,
+	// notifying the sleepers due then, and returns how far it moved
+	AdvanceToNextTimer() time.Duration
+	// NewTimerWithPriority is like NewTimer, but the timer fires before the
+	// timers of lower priority expiring at the same instant
+	NewTimerWithPriority(d time.Duration, priority int) Timer
+	// NextExpiration returns the earliest expiration of the sleepers of the
+	// FakeClock, or false if there are none
+	NextExpiration() (time.Time, bool)
+	// SetMinDurationWarning makes the FakeClock record an error whenever a
+	// timer or ticker is scheduled with a positive duration below d
+	SetMinDurationWarning(d time.Duration)
+	// AfterFuncSync is like AfterFunc, but f has returned by the time the
+	// advance which fires the timer returns
+	AfterFuncSync(d time.Duration, f func()) Timer
+	// SetAfterFuncTimeout makes advances wait for the functions of the
+	// AfterFuncSync timers for at most d of real time, 0 meaning forever
+	SetAfterFuncTimeout(d time.Duration)
+	// SetLocation makes the FakeClock report its time in loc from now on,
+	// without moving it
+	SetLocation(loc *time.Location)
+	// In returns a view of the FakeClock sharing its time and waiters but
+	// reporting its time in loc
+	In(loc *time.Location) FakeClock
+	// String describes the time and the waiters of the FakeClock, for
+	// debugging
+	String() string
+	// OnAdvance registers f to be called with the times before and after
+	// each advance of the FakeClock
+	OnAdvance(f func(from, to time.Time))
+	// Rewind moves the FakeClock back by d without notifying any sleeper
+	Rewind(d time.Duration)
+	// PendingWaiters returns the sleepers pending on the FakeClock with the
+	// time left before they expire, by order of expiration
+	PendingWaiters() []PendingWaiter
+	// AdvanceAndWait is like Advance, then waits for the functions of the
+	// AfterFunc timers it fired to return
+	AdvanceAndWait(d time.Duration)
+	// NewTickerSize is like NewTicker, with a channel buffering up to buf
+	// ticks
+	NewTickerSize(d time.Duration, buf int) Ticker
+	// BlockUntilAtMost blocks until the FakeClock has at most n sleepers, or
+	// the context is done
+	BlockUntilAtMost(ctx context.Context, n int) error
+	// Reset discards every sleeper of the FakeClock, releases its blockers
+	// and moves it to t, to reuse it as a fresh clock
+	Reset(t time.Time)
 }
 
 // NewRealClock returns a Clock which simply delegates calls to the actual time
 // package; it should be used by packages in production.
 func NewRealClock() Clock {
-	return &realClock{}
+	return &realClock{start: time.Now()}
 }
 
 // NewRealClockInLocation ...
 func NewRealClockInLocation(location *time.Location) Clock {
-	return &realClock{loc: location}
+	return &realClock{loc: location, start: time.Now()}
 }
 
 // NewFakeClock returns a FakeClock implementation which can be
-// manually advanced through time for testing. The initial time of the
-// FakeClock will be an arbitrary non-zero time.
-func NewFakeClock() FakeClock {
-	// use a fixture that does not fulfill Time.IsZero()
-	return NewFakeClockAt(time.Date(1984, time.April, 4, 0, 0, 0, 0, time.UTC))
+// manually advanced through time for testing. Unless the WithStartTime option
+// is given, the initial time of the FakeClock will be an arbitrary non-zero
+// time.
+func NewFakeClock(opts ...Option) FakeClock {
+	o := fakeClockOptions{
+		// use a fixture that does not fulfill Time.IsZero()
+		start: time.Date(1984, time.April, 4, 0, 0, 0, 0, time.UTC),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	fc := &fakeClock{
+		time:               o.start,
+		maxTicksPerAdvance: o.maxTicksPerAdvance,
+		autoAdvance:        o.autoAdvance,
+	}
+	fc.loc = fc.time.Location()
+	if o.loc != nil {
+		fc.loc = o.loc
+		fc.time = fc.time.In(o.loc)
+	}
+	fc.start = fc.time
+	fc.mono = fc.time
+	return fc
 }
 
 // NewFakeClockAt returns a FakeClock initialised at the given time.Time.
 func NewFakeClockAt(t time.Time) FakeClock {
-	return &fakeClock{
-		time: t,
-	}
+	return NewFakeClock(WithStartTime(t))
+}
+
+// NewFakeClockAtInLocation returns a FakeClock initialised at the given
+// time.Time, reporting its time in loc.
+func NewFakeClockAtInLocation(t time.Time, loc *time.Location) FakeClock {
+	return NewFakeClock(WithStartTime(t), WithLocation(loc))
 }
 
 type realClock struct{
-	loc *time.Location
+	loc   *time.Location
+	start time.Time
 }
 
 func (rc *realClock) Location() *time.Location {
@@ -103,6 +410,29 @@ func (rc *realClock) Sleep(d time.Duration) {
 	time.Sleep(d)
 }
 
+// AtLeast calls f, then sleeps until d has elapsed since the call started, so
+// that the whole takes at least d, e.g. to keep the duration of a response
+// from leaking timing information.
+func (rc *realClock) AtLeast(d time.Duration, f func()) {
+	atLeast(rc, d, f)
+}
+
+// SleepContext sleeps for d, unless the context is done first, in which case
+// it returns the context's error.
+func (rc *realClock) SleepContext(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func (rc *realClock) Now() time.Time {
 	if rc.loc != nil {
 		return time.Now().In(rc.loc)
@@ -118,14 +448,145 @@ func (rc *realClock) Until(t time.Time) time.Duration {
 	return t.Sub(rc.Now())
 }
 
+// TruncateToWindow returns the start of the window of the given size which
+// contains t. Windows are aligned on the time the realClock was created,
+// rather than on the zero time like time.Time.Truncate.
+func (rc *realClock) TruncateToWindow(t time.Time, window time.Duration) time.Time {
+	return truncateToWindow(rc.start, t, window)
+}
+
+// Parse parses value with time.ParseInLocation, in the location the realClock
+// was created with, or UTC if it has none.
+func (rc *realClock) Parse(layout, value string) (time.Time, error) {
+	loc := rc.loc
+	if loc == nil {
+		loc = time.UTC
+	}
+	return time.ParseInLocation(layout, value, loc)
+}
+
 func (rc *realClock) NewTicker(d time.Duration) Ticker {
 	return &realTicker{time.NewTicker(d)}
 }
 
+// Tick is time.Tick. The underlying ticker cannot be recovered by the garbage
+// collector, it "leaks", so Tick is only suitable for clients which tick for
+// their whole lifetime. It returns nil if d <= 0.
+func (rc *realClock) Tick(d time.Duration) <-chan time.Time {
+	return time.Tick(d)
+}
+
+// NewTickerFunc returns a Ticker calling f in its own goroutine on every tick
+// of a time.Ticker; its channel is nil. Stopping the ticker stops the calls.
+func (rc *realClock) NewTickerFunc(d time.Duration, f func()) Ticker {
+	t := &realTickerFunc{
+		realTicker: realTicker{time.NewTicker(d)},
+		stop:       make(chan struct{}),
+	}
+	go func() {
+		for {
+			select {
+			case <-t.C:
+				go f()
+			case <-t.stop:
+				return
+			}
+		}
+	}()
+	return t
+}
+
 type fakeClock struct {
-	sleepers []*sleeper
+	// sleepers is a heap of the pending sleepers, see sleeperHeap; armed
+	// counts the sleepers ever added to it
+	sleepers sleeperHeap
+	armed    uint64
 	blockers []*blocker
-	time     time.Time
+	// sleepBlockers are the callers of BlockUntilSleepers, counting only
+	// the sleepers of kind KindSleep
+	sleepBlockers []*blocker
+	// drainBlockers are the callers of BlockUntilAtMost, waiting for the
+	// number of sleepers to drop to their count or below
+	drainBlockers []*blocker
+	time          time.Time
+	// start is the initial time of the clock, the origin of its windows
+	start time.Time
+	// mono is the monotonic reading of the clock: the initial time plus
+	// every forward move of the clock
+	mono time.Time
+	// loc is the location the clock reports its time in, whatever the
+	// location of the times it is moved to, nil keeping theirs
+	loc *time.Location
+
+	maxTicksPerAdvance int
+	// minDuration is the positive duration below which scheduling records
+	// an error, 0 meaning never
+	minDuration time.Duration
+	// maxWaiters is the number of pending sleepers above which the clock
+	// panics, 0 meaning unlimited
+	maxWaiters int
+	errors     []error
+	// ticked is closed, then replaced, whenever a ticker fires
+	ticked chan struct{}
+	// idle is closed, then replaced, whenever the last sleeper goes away
+	idle chan struct{}
+	// leakFinalizers enables the detection of leaked tickers
+	leakFinalizers bool
+	// realTimeInAdvance accumulates the real time spent in advanceLocked
+	realTimeInAdvance time.Duration
+	// herdBatchSize is the number of sleepers notified between two releases
+	// of the lock during an advance, 0 meaning all of them
+	herdBatchSize int
+	// yieldAfterFire makes advances yield after each notified sleeper
+	yieldAfterFire bool
+	// fireBias shifts the time at which advances notify sleepers
+	fireBias time.Duration
+	// autoAdvance is the largest jump the clock makes on its own to reach
+	// a new sleeper, 0 meaning it never does
+	autoAdvance time.Duration
+	// fireLog receives a line per notified sleeper when set
+	fireLog io.Writer
+	// dimensions holds the independent time axes created by Dimension
+	dimensions map[string]*fakeClock
+	// deliverySink sees the sleepers expiring during advances
+	deliverySink func(e WaiterInfo, at time.Time) bool
+
+	// trackReads is set, atomically, while Now calls are counted per
+	// goroutine in reads, under readsL
+	trackReads uint32
+	readsL     sync.Mutex
+	reads      map[int64]int
+
+	// syncFuncs are the functions of the AfterFuncSync timers which fired,
+	// and the calls to advanceHooks, to be called by the holder of the write
+	// lock once it released it, see unlock
+	syncFuncs []func()
+	// afterFuncTimeout is how long advances wait for the function of an
+	// AfterFuncSync timer, in real time, 0 meaning forever
+	afterFuncTimeout time.Duration
+	// advanceHooks are the functions registered with OnAdvance
+	advanceHooks []func(from, to time.Time)
+	// trackers count the functions of the AfterFunc timers started while
+	// they are registered, see AdvanceAndWait
+	trackers []*funcTracker
+
+	// setTimeMetric and setWaitersMetric receive the state of the clock
+	// after each change, see RegisterMetrics
+	setTimeMetric    func(float64)
+	setWaitersMetric func(float64)
+
+	// random draws the durations of AdvanceRandom, randomSeed being the
+	// seed it was created with
+	random     *rand.Rand
+	randomSeed int64
+
+	// dilationTarget and dilation scale the durations given to Advance,
+	// see SetDilation
+	dilationTarget time.Time
+	dilation       func(remaining time.Duration) float64
+
+	// dedup holds the pending timers created by NewTimerDedup by key
+	dedup map[string]*sleeper
 
 	l sync.RWMutex
 }
@@ -139,16 +600,121 @@ type sleeper struct {
 	arg      interface{}
 	ch       chan time.Time
 	fc       *fakeClock // needed for Reset()
+	kind     WaiterKind
+	// name is the name given to AfterFuncNamed
+	name string
+	// dedupKey is the key given to NewTimerDedup
+	dedupKey string
+	// priority orders the sleepers expiring at the same instant, highest
+	// first
+	priority int
+	// release, when set, is called once the sleeper fired or was stopped
+	release func()
+	// index is the position of the sleeper in the sleepers of its clock, -1
+	// once it left them
+	index int
+	// seq orders the sleepers expiring at the same instant by arming
+	seq uint64
+}
+
+// WaiterKind tells what created a sleeper of a FakeClock.
+type WaiterKind int
+
+const (
+	// KindTimer is a sleeper from NewTimer or After
+	KindTimer WaiterKind = iota
+	// KindAfterFunc is a sleeper from AfterFunc
+	KindAfterFunc
+	// KindTicker is the pending tick of a ticker
+	KindTicker
+	// KindSleep is a caller of Sleep
+	KindSleep
+)
+
+func (k WaiterKind) String() string {
+	switch k {
+	case KindTimer:
+		return "timer"
+	case KindAfterFunc:
+		return "afterfunc"
+	case KindTicker:
+		return "ticker"
+	case KindSleep:
+		return "sleep"
+	}
+	return fmt.Sprintf("WaiterKind(%d)", int(k))
+}
+
+// WaiterInfo describes a sleeper of a FakeClock.
+type WaiterInfo struct {
+	Kind   WaiterKind
+	FireAt time.Time
+	// Name is the name given to AfterFuncNamed, if any
+	Name string
+	// Priority is the priority given to NewTimerWithPriority, 0 otherwise
+	Priority int
+}
+
+// pendingOn reports whether s is among the sleepers of fc. The caller must
+// hold the lock of fc.
+func (s *sleeper) pendingOn(fc *fakeClock) bool {
+	return s.index >= 0 && s.index < len(fc.sleepers) && fc.sleepers[s.index] == s
+}
+
+// sleeperHeap is a min-heap of sleepers, for container/heap, ordered by
+// expiration then by arming order, so that scheduling and removing a sleeper
+// are O(log n) and the next expiring one is always first. Iterating over it
+// directly gives no particular order, see sorted.
+type sleeperHeap []*sleeper
+
+func (h sleeperHeap) Len() int { return len(h) }
+func (h sleeperHeap) Less(i, j int) bool {
+	if !h[i].until.Equal(h[j].until) {
+		return h[i].until.Before(h[j].until)
+	}
+	return h[i].seq < h[j].seq
+}
+func (h sleeperHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *sleeperHeap) Push(x interface{}) {
+	s := x.(*sleeper)
+	s.index = len(*h)
+	*h = append(*h, s)
+}
+func (h *sleeperHeap) Pop() interface{} {
+	old := *h
+	s := old[len(old)-1]
+	old[len(old)-1] = nil
+	s.index = -1
+	*h = old[:len(old)-1]
+	return s
+}
+
+// sorted returns a copy of the sleepers by expiration then arming order.
+func (h sleeperHeap) sorted() []*sleeper {
+	sorted := append(sleeperHeap(nil), h...)
+	sort.Slice(sorted, sorted.Less)
+	return sorted
+}
+
+func (s *sleeper) info() WaiterInfo {
+	return WaiterInfo{Kind: s.kind, FireAt: s.until, Name: s.name, Priority: s.priority}
 }
 
-func (s *sleeper) awaken(now time.Time) {
+func (s *sleeper) awaken(now time.Time) bool {
 	if atomic.CompareAndSwapUint32(&s.done, 0, 1) {
 		s.callback(s.arg, now)
+		return true
 	}
+	return false
 }
 func (s *sleeper) C() <-chan time.Time { return s.ch }
 func (s *sleeper) T() *time.Timer      { return nil }
 func (s *sleeper) Reset(d time.Duration) bool {
+	s.fc.checkDuration(d)
 	active := s.Stop()
 	s.until = s.fc.Now().Add(d)
 	defer s.fc.addTimer(s)
@@ -158,9 +724,11 @@ func (s *sleeper) Reset(d time.Duration) bool {
 func (s *sleeper) Stop() bool {
 	stopped := atomic.CompareAndSwapUint32(&s.done, 0, 1)
 	if stopped {
-		// Expire the timer and notify blockers
-		s.until = s.fc.Now()
-		s.fc.Advance(0)
+		// Remove the timer and notify blockers
+		s.fc.remove(s)
+		if s.release != nil {
+			s.release()
+		}
 	}
 	return stopped
 }
@@ -180,27 +748,233 @@ func (fc *fakeClock) After(d time.Duration) <-chan time.Time {
 // NewTimer creates a new Timer that will send the current time on its channel
 // after the given duration elapses on the fake clock.
 func (fc *fakeClock) NewTimer(d time.Duration) Timer {
-	sendTime := func(c interface{}, now time.Time) {
-		c.(chan time.Time) <- now
+	return fc.newTimer(d, KindTimer)
+}
+
+func (fc *fakeClock) newTimer(d time.Duration, kind WaiterKind) *sleeper {
+	if kind != KindTicker {
+		// The pending tick of a ticker is legitimately short after an
+		// advance; its period is checked instead.
+		fc.checkDuration(d)
 	}
 	done := make(chan time.Time, 1)
 	s := &sleeper{
 		fc:       fc,
-		until:    fc.time.Add(d),
+		until:    fc.Now().Add(d),
+		callback: sendTime,
+		arg:      done,
+		ch:       done,
+		kind:     kind,
+	}
+	fc.addTimer(s)
+	return s
+}
+
+// AfterFuncSync waits for the duration to elapse on the fakeClock and then calls
+// f, like AfterFunc, except that f runs in the goroutine advancing the clock,
+// once the clock is unlocked but before the advance returns, so that a test
+// can assert on its side effects right after advancing. f may use the clock,
+// e.g. to schedule another timer; the functions of the timers firing in the
+// same advance run one after the other, in order. A timer created with a
+// non-positive duration calls f before AfterFuncSync returns. See
+// SetAfterFuncTimeout to keep a blocking f from hanging the advance.
+func (fc *fakeClock) AfterFuncSync(d time.Duration, f func()) Timer {
+	fc.checkDuration(d)
+	s := &sleeper{
+		fc:    fc,
+		until: fc.Now().Add(d),
+		callback: func(f interface{}, _ time.Time) {
+			// Called with the lock held.
+			fn := f.(func())
+			if timeout := fc.afterFuncTimeout; timeout > 0 {
+				fn = fc.withWatchdog(fn, timeout)
+			}
+			fc.syncFuncs = append(fc.syncFuncs, fn)
+		},
+		arg:  f,
+		kind: KindAfterFunc,
+	}
+	fc.addTimer(s)
+	return s
+}
+
+// NewTimerContext creates a Timer like NewTimer, which is stopped as soon as
+// the context is done, so that timers of canceled operations don't linger as
+// sleepers. The timer is stopped by a goroutine watching the context, shortly
+// after it is done, or right away if it already is. Stopping the timer
+// explicitly, before or after the context is done, is harmless, as is the
+// context being done after the timer fired; once the timer fired or was
+// stopped, the context is no longer watched and resetting the timer does not
+// tie it to the context again.
+func (fc *fakeClock) NewTimerContext(ctx context.Context, d time.Duration) Timer {
+	fc.checkDuration(d)
+	done := make(chan time.Time, 1)
+	released := make(chan struct{})
+	var once sync.Once
+	s := &sleeper{
+		fc:    fc,
+		until: fc.Now().Add(d),
+		callback: func(c interface{}, now time.Time) {
+			sendTime(c, now)
+			once.Do(func() { close(released) })
+		},
+		arg:     done,
+		ch:      done,
+		release: func() { once.Do(func() { close(released) }) },
+	}
+	if ctx.Err() != nil {
+		// Never armed, the same as a timer stopped right away.
+		s.done = 1
+		return s
+	}
+	fc.addTimer(s)
+	if ctx.Done() != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				s.Stop()
+			case <-released:
+			}
+		}()
+	}
+	return s
+}
+
+// NewTimerWithPriority creates a Timer like NewTimer, with a priority: when an
+// advance notifies several sleepers expiring at the same instant, those with a
+// higher priority are notified first, and those with the same priority in the
+// order they were scheduled. Other sleepers have priority 0.
+func (fc *fakeClock) NewTimerWithPriority(d time.Duration, priority int) Timer {
+	fc.checkDuration(d)
+	done := make(chan time.Time, 1)
+	s := &sleeper{
+		fc:       fc,
+		until:    fc.Now().Add(d),
 		callback: sendTime,
 		arg:      done,
 		ch:       done,
+		priority: priority,
 	}
 	fc.addTimer(s)
 	return s
 }
 
+// NewTimerDedup creates a Timer like NewTimer, unless a timer created with the
+// same key is still pending, in which case that timer is reset to fire after d
+// and returned instead, debouncing the timers by key: scheduling the same
+// logical timeout several times does not inflate the number of sleepers. Once
+// the keyed timer has fired or been stopped, a new one is created.
+func (fc *fakeClock) NewTimerDedup(key string, d time.Duration) Timer {
+	fc.checkDuration(d)
+	fc.l.Lock()
+	defer fc.unlock()
+	if s, ok := fc.dedup[key]; ok {
+		// Re-arm the pending timer in place, as ResetAll does, so that no
+		// advance sees it missing.
+		fc.removeLocked(s)
+		s.until = fc.time.Add(d)
+		if d > 0 {
+			fc.pushLocked(s)
+			return s
+		}
+		fc.fireLocked(s, fc.time)
+		fc.blockers = notifyBlockers(fc.blockers, len(fc.sleepers))
+		fc.notifySleepBlockersLocked()
+		fc.notifyDrainBlockersLocked()
+		fc.notifyIdleLocked()
+		return s
+	}
+	done := make(chan time.Time, 1)
+	s := &sleeper{
+		fc:       fc,
+		until:    fc.time.Add(d),
+		callback: sendTime,
+		arg:      done,
+		ch:       done,
+		kind:     KindTimer,
+		dedupKey: key,
+	}
+	fc.addTimerLocked(s)
+	return s
+}
+
+// forgetDedupLocked removes s from the timers of NewTimerDedup once it fired
+// or was stopped. The caller must hold the write lock.
+func (fc *fakeClock) forgetDedupLocked(s *sleeper) {
+	if s.dedupKey != "" && fc.dedup[s.dedupKey] == s {
+		delete(fc.dedup, s.dedupKey)
+	}
+}
+
+// NewUnarmedTimer creates a new Timer which belongs to the fake clock but is not
+// part of its sleepers until Reset arms it. Stopping an unarmed timer is a
+// no-op which returns false.
+func (fc *fakeClock) NewUnarmedTimer() Timer {
+	done := make(chan time.Time, 1)
+	return &sleeper{
+		fc:       fc,
+		done:     1, // inactive until Reset
+		callback: sendTime,
+		arg:      done,
+		ch:       done,
+	}
+}
+
+// Done returns a channel which is closed when the fakeClock reaches the
+// absolute time t, or right away if it already has. Until then it counts as a
+// sleeper of the fakeClock.
+func (fc *fakeClock) Done(t time.Time) <-chan struct{} {
+	done := make(chan struct{})
+	fc.addTimer(&sleeper{
+		fc:       fc,
+		until:    t,
+		callback: closeChan,
+		arg:      done,
+	})
+	return done
+}
+
+// closeChan is the sleeper callback used by Done.
+func closeChan(c interface{}, _ time.Time) {
+	close(c.(chan struct{}))
+}
+
+// sendTime is the sleeper callback used by channel based timers. Like the
+// time package, it drops the time rather than block when the channel still
+// holds the time of a previous fire, as after resetting a fired timer without
+// draining its channel.
+func sendTime(c interface{}, now time.Time) {
+	select {
+	case c.(chan time.Time) <- now:
+	default:
+	}
+}
+
 // AfterFunc waits for the duration to elapse on the fake clock and then calls f
 // in its own goroutine.
 // It returns a Timer that can be used to cancel the call using its Stop method.
 func (fc *fakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	return fc.AfterFuncNamed(d, "", f)
+}
+
+// AfterFuncNamed is like AfterFunc, but attaches name to the timer so that
+// PendingAfterFuncs can tell what is scheduled, e.g. a cleanup and a retry.
+func (fc *fakeClock) AfterFuncNamed(d time.Duration, name string, f func()) Timer {
+	fc.checkDuration(d)
 	goFunc := func(fn interface{}, _ time.Time) {
-		go fn.(func())()
+		// Called with the write lock held.
+		trackers := fc.trackers
+		for _, t := range trackers {
+			t.add(1)
+		}
+		go func() {
+			defer func() {
+				for _, t := range trackers {
+					t.add(-1)
+				}
+			}()
+			fn.(func())()
+		}()
 	}
 	s := &sleeper{
 		fc:       fc,
@@ -208,6 +982,8 @@ func (fc *fakeClock) AfterFunc(d time.Duration, f func()) Timer {
 		callback: goFunc,
 		arg:      f,
 		// zero-valued ch, the same as it is in the `time` pkg
+		kind: KindAfterFunc,
+		name: name,
 	}
 	fc.addTimer(s)
 	return s
@@ -215,19 +991,42 @@ func (fc *fakeClock) AfterFunc(d time.Duration, f func()) Timer {
 
 func (fc *fakeClock) addTimer(s *sleeper) {
 	fc.l.Lock()
-	defer fc.l.Unlock()
+	defer fc.unlock()
+	fc.addTimerLocked(s)
+}
+
+// addTimerLocked is addTimer for a caller holding the write lock.
+func (fc *fakeClock) addTimerLocked(s *sleeper) {
 	now := fc.time
 	if now.Sub(s.until) >= 0 {
 		// special case - trigger immediately
-		s.awaken(now)
+		fc.fireLocked(s, now)
 	} else {
+		fc.checkMaxWaitersLocked(len(fc.sleepers) + 1)
 		// otherwise, add to the set of sleepers
-		fc.sleepers = append(fc.sleepers, s)
+		fc.pushLocked(s)
 		// and notify any blockers
 		fc.blockers = notifyBlockers(fc.blockers, len(fc.sleepers))
+		fc.notifySleepBlockersLocked()
+		fc.notifyDrainBlockersLocked()
+		fc.autoAdvanceLocked(s)
 	}
 }
 
+// remove removes a stopped sleeper from the sleepers of fakeClock, leaving
+// the others untouched, and notifies the callers waiting for the number of
+// sleepers to change.
+func (fc *fakeClock) remove(s *sleeper) {
+	fc.l.Lock()
+	defer fc.unlock()
+	fc.removeLocked(s)
+	fc.forgetDedupLocked(s)
+	fc.blockers = notifyBlockers(fc.blockers, len(fc.sleepers))
+	fc.notifySleepBlockersLocked()
+	fc.notifyDrainBlockersLocked()
+	fc.notifyIdleLocked()
+}
+
 // notifyBlockers notifies all the blockers waiting until the
 // given number of sleepers are waiting on the fakeClock. It
 // returns an updated slice of blockers (i.e. those still waiting)
@@ -244,76 +1043,1587 @@ func notifyBlockers(blockers []*blocker, count int) (newBlockers []*blocker) {
 
 // Sleep blocks until the given duration has passed on the fakeClock
 func (fc *fakeClock) Sleep(d time.Duration) {
-	<-fc.After(d)
+	<-fc.newTimer(d, KindSleep).C()
+}
+
+// AtLeast calls f, then sleeps on the fakeClock until d has elapsed on it since
+// the call started, so that the padding is driven by Advance. If the clock has
+// moved by d or more while f ran, AtLeast returns right after f.
+func (fc *fakeClock) AtLeast(d time.Duration, f func()) {
+	atLeast(fc, d, f)
+}
+
+// SleepContext blocks until d has passed on the fakeClock, like Sleep, unless
+// the context is done first, in which case it returns the context's error. A
+// context which is already done wins even if d is not positive. When the
+// context wins, the sleeper is removed right away, so that it no longer counts
+// for BlockUntil.
+func (fc *fakeClock) SleepContext(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s := fc.newTimer(d, KindSleep)
+	select {
+	case <-s.C():
+		return nil
+	case <-ctx.Done():
+		s.Stop()
+		return ctx.Err()
+	}
 }
 
 // Time returns the current time of the fakeClock
 func (fc *fakeClock) Now() time.Time {
+	if atomic.LoadUint32(&fc.trackReads) == 1 {
+		fc.countRead()
+	}
 	fc.l.RLock()
 	t := fc.time
 	fc.l.RUnlock()
 	return t
 }
 
-// Since returns the duration that has passed since the given time on the fakeClock
-func (fc *fakeClock) Since(t time.Time) time.Duration {
-	return fc.Now().Sub(t)
+// TrackGoroutineReads turns on or off the counting of the calls to Now made by
+// each goroutine, including the ones the fakeClock makes on behalf of its
+// callers, for instance when creating a timer. This helps finding a goroutine
+// spinning on the clock. Identifying the calling goroutine is expensive, so
+// it is off by default.
+func (fc *fakeClock) TrackGoroutineReads(enabled bool) {
+	var v uint32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreUint32(&fc.trackReads, v)
 }
 
-// Until returns the duration until the given time on the fakeClock
-func (fc *fakeClock) Until(t time.Time) time.Duration {
-	return t.Sub(fc.Now())
+// PerGoroutineReads returns a copy of the number of calls to Now counted for
+// each goroutine while TrackGoroutineReads was on, keyed by goroutine id.
+func (fc *fakeClock) PerGoroutineReads() map[int64]int {
+	fc.readsL.Lock()
+	defer fc.readsL.Unlock()
+	reads := make(map[int64]int, len(fc.reads))
+	for id, n := range fc.reads {
+		reads[id] = n
+	}
+	return reads
 }
 
-func (fc *fakeClock) Location() *time.Location {
-	return fc.time.Location()
+func (fc *fakeClock) countRead() {
+	id := goroutineID()
+	fc.readsL.Lock()
+	if fc.reads == nil {
+		fc.reads = make(map[int64]int)
+	}
+	fc.reads[id]++
+	fc.readsL.Unlock()
 }
 
-func (fc *fakeClock) NewTicker(d time.Duration) Ticker {
-	ft := &fakeTicker{
-		c:      make(chan time.Time, 1),
-		stop:   make(chan bool, 1),
-		clock:  fc,
-		period: d,
+// goroutineID returns the id of the calling goroutine, parsed from the header
+// of its stack trace: "goroutine 42 [running]:".
+func goroutineID() int64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = b[len("goroutine "):]
+	for i, c := range b {
+		if c == ' ' {
+			b = b[:i]
+			break
+		}
 	}
-	go ft.tick()
-	return ft
+	id, _ := strconv.ParseInt(string(b), 10, 64)
+	return id
 }
 
-// Advance advances fakeClock to a new point in time, ensuring channels from any
-// previous invocations of After are notified appropriately before returning
-func (fc *fakeClock) Advance(d time.Duration) {
+// NowBytes appends the current time of the fakeClock, formatted as RFC3339Nano,
+// to buf and returns the extended buffer. Reusing buf across calls avoids the
+// allocation done by Now().Format(...).
+func (fc *fakeClock) NowBytes(buf []byte) []byte {
+	return fc.Now().AppendFormat(buf, time.RFC3339Nano)
+}
+
+// State returns the current time of the fakeClock and the earliest expiration
+// among its sleepers, both read under the same lock so that a concurrent
+// Advance cannot be observed halfway.
+func (fc *fakeClock) State() (now time.Time, next time.Time, hasNext bool) {
+	fc.l.RLock()
+	defer fc.l.RUnlock()
+	next, hasNext = fc.nextExpirationLocked()
+	return fc.time, next, hasNext
+}
+
+// WouldFire reports whether Advance(d) would notify at least one of the
+// current sleepers, without advancing the fakeClock.
+func (fc *fakeClock) WouldFire(d time.Duration) bool {
+	fc.l.RLock()
+	defer fc.l.RUnlock()
+	next, ok := fc.nextExpirationLocked()
+	return ok && !next.Add(fc.fireBias).After(fc.time.Add(d))
+}
+
+// StopWhere stops the sleepers for which pred returns true, as if Stop was
+// called on each of them, and returns how many were stopped. Stopping the
+// pending tick of a ticker stops the ticker from ticking. pred is called
+// without holding the fakeClock's lock.
+func (fc *fakeClock) StopWhere(pred func(WaiterInfo) bool) int {
+	fc.l.RLock()
+	sleepers := append([]*sleeper(nil), fc.sleepers...)
+	infos := make([]WaiterInfo, len(sleepers))
+	for i, s := range sleepers {
+		infos[i] = s.info()
+	}
+	fc.l.RUnlock()
+	stopped := 0
+	for i, s := range sleepers {
+		if pred(infos[i]) && s.Stop() {
+			stopped++
+		}
+	}
+	return stopped
+}
+
+// PublishExpvar publishes the state of the fakeClock through the expvar
+// package, under name, as a JSON object holding its current time and number of
+// sleepers. expvars are global and cannot be unpublished: each clock needs its
+// own name, and publishing an existing name panics.
+func (fc *fakeClock) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		fc.l.RLock()
+		defer fc.l.RUnlock()
+		return map[string]interface{}{
+			"now":     fc.time.Format(time.RFC3339Nano),
+			"waiters": len(fc.sleepers),
+		}
+	}))
+}
+
+// RegisterMetrics registers callbacks exposing the state of the fakeClock, for
+// instance as gauges of a metrics registry, without depending on any metrics
+// library: setTime receives the current time of the clock, as fractional
+// seconds since the Unix epoch, and setWaiters its number of sleepers. Both
+// are called right away with the current values, then after each advance of
+// the clock and each sleeper scheduled or stopped, once the clock is unlocked
+// so that they may use it. Updates made from different goroutines may be
+// delivered concurrently. Either callback may be nil; passing nil for both
+// removes them.
+func (fc *fakeClock) RegisterMetrics(setTime func(float64), setWaiters func(float64)) {
 	fc.l.Lock()
-	defer fc.l.Unlock()
-	end := fc.time.Add(d)
-	var newSleepers []*sleeper
-	for _, s := range fc.sleepers {
-		if end.Sub(s.until) >= 0 {
-			s.awaken(end)
-		} else {
-			newSleepers = append(newSleepers, s)
+	fc.setTimeMetric = setTime
+	fc.setWaitersMetric = setWaiters
+	fc.l.Unlock()
+	fc.publishMetrics()
+}
+
+// unlock releases the write lock of fakeClock after a change, then does what
+// must follow it: it calls the functions queued in syncFuncs while the caller
+// held the lock, those of the AfterFuncSync timers which fired and the
+// OnAdvance hooks, and passes the new state to the callbacks of
+// RegisterMetrics. The functions are taken before unlocking, so that they run
+// in the goroutine which made the change, before it returns, rather than in
+// whichever goroutine locks the clock next.
+func (fc *fakeClock) unlock() {
+	funcs := fc.syncFuncs
+	fc.syncFuncs = nil
+	fc.l.Unlock()
+	for _, f := range funcs {
+		f()
+	}
+	fc.publishMetrics()
+}
+
+// whileUnlocked releases the write lock of fakeClock, held by the caller,
+// while calling f. The functions the caller queued in syncFuncs are set aside
+// meanwhile, so that the goroutines taking the lock in between do not run
+// them.
+func (fc *fakeClock) whileUnlocked(f func()) {
+	queued := fc.syncFuncs
+	fc.syncFuncs = nil
+	fc.l.Unlock()
+	f()
+	fc.l.Lock()
+	fc.syncFuncs = append(queued, fc.syncFuncs...)
+}
+
+// publishMetrics passes the state of fakeClock to the callbacks registered
+// with RegisterMetrics. The caller must not hold the lock.
+func (fc *fakeClock) publishMetrics() {
+	fc.l.RLock()
+	setTime, setWaiters := fc.setTimeMetric, fc.setWaitersMetric
+	now, waiters := fc.time, len(fc.sleepers)
+	fc.l.RUnlock()
+	if setTime != nil {
+		setTime(float64(now.UnixNano()) / float64(time.Second))
+	}
+	if setWaiters != nil {
+		setWaiters(float64(waiters))
+	}
+}
+
+// pushLocked adds s to the sleepers of fakeClock, after the ones already
+// armed, and makes it the timer of its NewTimerDedup key unless another one
+// is pending. The caller must hold the write lock.
+func (fc *fakeClock) pushLocked(s *sleeper) {
+	s.seq = fc.armed
+	fc.armed++
+	heap.Push(&fc.sleepers, s)
+	if s.dedupKey != "" && fc.dedup[s.dedupKey] == nil {
+		if fc.dedup == nil {
+			fc.dedup = make(map[string]*sleeper)
 		}
+		fc.dedup[s.dedupKey] = s
+	}
+}
+
+// removeLocked removes s from the sleepers of fakeClock and reports whether
+// it was among them. The caller must hold the write lock.
+func (fc *fakeClock) removeLocked(s *sleeper) bool {
+	if !s.pendingOn(fc) {
+		return false
+	}
+	heap.Remove(&fc.sleepers, s.index)
+	return true
+}
+
+// errNotWaiting is returned for timers which are not sleepers of the fakeClock.
+var errNotWaiting = errors.New("clockwork: timer is not waiting on this clock")
+
+// FireOnly fires t alone: the fakeClock moves to t's expiration, unless it is
+// already past it, and t is notified while every other sleeper stays pending,
+// even those which expire before t. This deliberately breaks the consistency
+// of the timeline to focus a test on a single timer. It returns an error if t
+// is not currently waiting on the fakeClock.
+func (fc *fakeClock) FireOnly(t Timer) error {
+	fc.l.Lock()
+	defer fc.unlock()
+	s, ok := t.(*sleeper)
+	if !ok || !fc.removeLocked(s) {
+		return errNotWaiting
 	}
-	fc.sleepers = newSleepers
+	if s.until.After(fc.time) {
+		fc.moveToLocked(s.until)
+	}
+	fc.fireLocked(s, fc.time)
 	fc.blockers = notifyBlockers(fc.blockers, len(fc.sleepers))
-	fc.time = end
+	fc.notifySleepBlockersLocked()
+	fc.notifyDrainBlockersLocked()
+	fc.notifyIdleLocked()
+	return nil
 }
 
-// BlockUntil will block until the fakeClock has the given number of sleepers
-// (callers of Sleep or After)
-func (fc *fakeClock) BlockUntil(n int) {
+// FiresBeforeTimer reports whether timer a currently expires strictly before
+// timer b, so that a test can state an ordering such as "the timeout fires
+// before the retry". Timers expiring at the same time are not ordered. It
+// returns an error if either timer is not waiting on the fakeClock, for
+// instance because it already fired or was stopped.
+func (fc *fakeClock) FiresBeforeTimer(a, b Timer) (bool, error) {
+	fc.l.RLock()
+	defer fc.l.RUnlock()
+	var sa, sb *sleeper
+	for _, s := range fc.sleepers {
+		switch s {
+		case a:
+			sa = s
+		case b:
+			sb = s
+		}
+	}
+	if sa == nil || sb == nil {
+		return false, errNotWaiting
+	}
+	return sa.until.Before(sb.until), nil
+}
+
+// PeekFire returns the time t delivered on its channel when it fired, and true,
+// without consuming it: channels cannot be peeked at, so the time is received
+// then sent back on the channel, which only holds one value. If t has not
+// fired, its value was already received, or t has no channel, as with
+// AfterFunc, or does not belong to the fakeClock, PeekFire returns false. The
+// clock is locked while peeking so that t cannot fire in between, but a
+// concurrent receiver may block until the value is sent back.
+func (fc *fakeClock) PeekFire(t Timer) (time.Time, bool) {
+	s, ok := t.(*sleeper)
+	if !ok || s.fc != fc || s.ch == nil {
+		return time.Time{}, false
+	}
 	fc.l.Lock()
-	// Fast path: current number of sleepers is what we're looking for
-	if len(fc.sleepers) == n {
-		fc.l.Unlock()
-		return
+	defer fc.l.Unlock()
+	select {
+	case now := <-s.ch:
+		s.ch <- now
+		return now, true
+	default:
+		return time.Time{}, false
 	}
-	// Otherwise, set up a new blocker
-	b := &blocker{
-		count: n,
-		ch:    make(chan struct{}),
+}
+
+// Reset prepares fakeClock for reuse, e.g. by the next case of a table-driven
+// test, as if it was new and started at t: all its sleepers are discarded
+// without being notified, the callers of BlockUntil, BlockUntilSleepers and
+// BlockUntilAtMost are released, and its time, initial time and monotonic
+// reading are set to t. The timers and tickers obtained before Reset become
+// inert: they never fire again, Stop reports they were not active, and their
+// tickers should still be stopped to end their goroutine. A stale timer can be
+// armed again with its Reset method. Its settings, such as the hooks of
+// OnAdvance or SetFireBias, are kept.
+func (fc *fakeClock) Reset(t time.Time) {
+	fc.l.Lock()
+	defer fc.unlock()
+	for _, s := range fc.sleepers {
+		if atomic.CompareAndSwapUint32(&s.done, 0, 1) && s.release != nil {
+			s.release()
+		}
+	}
+	fc.sleepers = nil
+	fc.dedup = nil
+	for _, blockers := range [][]*blocker{fc.blockers, fc.sleepBlockers, fc.drainBlockers} {
+		for _, b := range blockers {
+			close(b.ch)
+		}
+	}
+	fc.blockers, fc.sleepBlockers, fc.drainBlockers = nil, nil, nil
+	fc.notifyIdleLocked()
+	t = fc.inLocation(t)
+	fc.time = t
+	fc.start = t
+	fc.mono = t
+}
+
+// ResetAll resets every timer of updates to fire after its duration, as Reset
+// would, but as a single operation: no advance of the fakeClock can happen
+// while only some of the timers are reset. Timers whose duration is not
+// positive fire right away, in no particular order, and the others are armed
+// in no particular order either. Every timer must have been
+// created by the fakeClock, armed or not; otherwise an error is returned and
+// none of them is reset.
+func (fc *fakeClock) ResetAll(updates map[Timer]time.Duration) error {
+	sleepers := make(map[*sleeper]time.Duration, len(updates))
+	for t, d := range updates {
+		s, ok := t.(*sleeper)
+		if !ok || s.fc != fc {
+			return fmt.Errorf("clockwork: cannot reset %T, it does not belong to this clock", t)
+		}
+		sleepers[s] = d
+	}
+	fc.l.Lock()
+	defer fc.unlock()
+	pending := len(fc.sleepers)
+	for s, d := range sleepers {
+		if s.pendingOn(fc) {
+			pending--
+		}
+		if d > 0 {
+			pending++
+		}
+	}
+	fc.checkMaxWaitersLocked(pending)
+	now := fc.time
+	for s, d := range sleepers {
+		fc.removeLocked(s)
+		atomic.StoreUint32(&s.done, 0)
+		s.until = now.Add(d)
+		if now.Sub(s.until) >= 0 {
+			fc.fireLocked(s, now)
+		} else {
+			fc.pushLocked(s)
+		}
+	}
+	fc.blockers = notifyBlockers(fc.blockers, len(fc.sleepers))
+	fc.notifySleepBlockersLocked()
+	fc.notifyDrainBlockersLocked()
+	fc.notifyIdleLocked()
+	return nil
+}
+
+// Waiters returns a description of each sleeper pending on the fakeClock,
+// sorted by expiration. Sleepers expiring at the same time are in the order
+// in which they were armed.
+func (fc *fakeClock) Waiters() []WaiterInfo {
+	_, infos := fc.snapshotWaiters()
+	return infos
+}
+
+// snapshotWaiters returns the current time of the fakeClock and a description
+// of its sleepers, read together and sorted like Waiters does.
+func (fc *fakeClock) snapshotWaiters() (time.Time, []WaiterInfo) {
+	fc.l.RLock()
+	now := fc.time
+	sleepers := fc.sleepers.sorted()
+	fc.l.RUnlock()
+	infos := make([]WaiterInfo, len(sleepers))
+	for i, s := range sleepers {
+		infos[i] = s.info()
+	}
+	return now, infos
+}
+
+// PendingWaiter describes a sleeper pending on a FakeClock.
+type PendingWaiter struct {
+	Kind   WaiterKind
+	FireAt time.Time
+	// Remaining is the time left before the sleeper expires, negative for
+	// an overdue sleeper, e.g. after SetTime or Rewind
+	Remaining time.Duration
+}
+
+// PendingWaiters returns a description of each sleeper pending on the
+// fakeClock, with the time left before it expires, in the order of Waiters.
+// The remaining durations are relative to the time of the fakeClock read
+// together with the sleepers.
+func (fc *fakeClock) PendingWaiters() []PendingWaiter {
+	now, infos := fc.snapshotWaiters()
+	pending := make([]PendingWaiter, len(infos))
+	for i, info := range infos {
+		pending[i] = PendingWaiter{Kind: info.Kind, FireAt: info.FireAt, Remaining: info.FireAt.Sub(now)}
+	}
+	return pending
+}
+
+// String describes the fakeClock for debugging: its current time, then the
+// kind of each of its sleepers and the time left before it expires, by order
+// of expiration, e.g. "clock@1984-04-04T00:00:00Z waiters=[+1s timer, +5s
+// ticker]". The format may change and should not be parsed.
+func (fc *fakeClock) String() string {
+	now, infos := fc.snapshotWaiters()
+	var b strings.Builder
+	fmt.Fprintf(&b, "clock@%s waiters=[", now.Format(time.RFC3339Nano))
+	for i, info := range infos {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		left := info.FireAt.Sub(now)
+		if left >= 0 {
+			b.WriteString("+")
+		}
+		fmt.Fprintf(&b, "%v %v", left, info.Kind)
+	}
+	b.WriteString("]")
+	return b.String()
+}
+
+// PendingAfterFuncs returns the names of the AfterFunc timers of the fakeClock
+// which have neither fired nor been stopped, by order of expiration. Timers
+// created with AfterFunc rather than AfterFuncNamed are listed with an empty
+// name.
+func (fc *fakeClock) PendingAfterFuncs() []string {
+	fc.l.RLock()
+	var pending []*sleeper
+	for _, s := range fc.sleepers.sorted() {
+		if s.kind == KindAfterFunc {
+			pending = append(pending, s)
+		}
+	}
+	fc.l.RUnlock()
+	names := make([]string, len(pending))
+	for i, s := range pending {
+		names[i] = s.name
+	}
+	return names
+}
+
+// TimeToDrain returns how far the fakeClock must advance for every sleeper
+// currently pending to fire: the latest expiration among them, shifted by the
+// bias of SetFireBias, minus the current time. The pending ticks of tickers are left out since a ticker never
+// drains; when only tickers, or nothing, are pending, TimeToDrain returns 0.
+// Sleepers scheduled later, for instance by the goroutines woken up on the
+// way, are not accounted for.
+func (fc *fakeClock) TimeToDrain() time.Duration {
+	fc.l.RLock()
+	defer fc.l.RUnlock()
+	var drain time.Duration
+	for _, s := range fc.sleepers {
+		if s.kind == KindTicker {
+			continue
+		}
+		if d := s.until.Add(fc.fireBias).Sub(fc.time); d > drain {
+			drain = d
+		}
+	}
+	return drain
+}
+
+// NextExpiration returns the earliest expiration among the sleepers of the
+// fakeClock and true, or the zero time and false when none is pending, without
+// side effects: e.g. to decide whether to keep stepping with
+// AdvanceToNextTimer.
+func (fc *fakeClock) NextExpiration() (time.Time, bool) {
+	fc.l.RLock()
+	defer fc.l.RUnlock()
+	return fc.nextExpirationLocked()
+}
+
+// nextExpirationLocked returns the earliest expiration among the sleepers.
+// The caller must hold the lock.
+func (fc *fakeClock) nextExpirationLocked() (time.Time, bool) {
+	if len(fc.sleepers) == 0 {
+		return time.Time{}, false
+	}
+	return fc.sleepers[0].until, true
+}
+
+// Since returns the duration that has passed since the given time on the fakeClock
+func (fc *fakeClock) Since(t time.Time) time.Duration {
+	return fc.Now().Sub(t)
+}
+
+// MonotonicSince returns the time elapsed since start on the monotonic timeline
+// of the fakeClock, the counterpart of the monotonic reading carried by the
+// times of the real clock. That timeline begins at the initial time of the
+// fakeClock and only moves when the clock moves forward, by the same amount,
+// so it matches Now until the wall time of the clock is stepped backward
+// (e.g. a negative Advance): steps backward do not move it. start is
+// interpreted on the monotonic timeline, so it should come from Now before any
+// such step for the result to be meaningful.
+func (fc *fakeClock) MonotonicSince(start time.Time) time.Duration {
+	fc.l.RLock()
+	defer fc.l.RUnlock()
+	return fc.mono.Sub(start)
+}
+
+// moveToLocked sets the time of the fakeClock, accounting forward moves on its
+// monotonic timeline. The caller must hold the write lock.
+func (fc *fakeClock) moveToLocked(t time.Time) {
+	if t.After(fc.time) {
+		fc.mono = fc.mono.Add(t.Sub(fc.time))
+	}
+	fc.time = fc.inLocation(t)
+}
+
+// inLocation returns t in the location of the fakeClock, see SetLocation.
+func (fc *fakeClock) inLocation(t time.Time) time.Time {
+	if fc.loc == nil {
+		return t
+	}
+	return t.In(fc.loc)
+}
+
+// Until returns the duration until the given time on the fakeClock
+func (fc *fakeClock) Until(t time.Time) time.Duration {
+	return t.Sub(fc.Now())
+}
+
+func (fc *fakeClock) Location() *time.Location {
+	fc.l.RLock()
+	defer fc.l.RUnlock()
+	return fc.time.Location()
+}
+
+// SetLocation makes the fakeClock report its time in loc, like time.Time.In:
+// the instant of the clock does not change, only the location of the times it
+// returns and notifies its sleepers with. The clock stays in loc when it is
+// later moved to times of another location, e.g. by AdvanceTo, SetTime or
+// Reset, or to the expiration of a sleeper armed before. Advances operate on
+// the instant, so
+// a day-long Advance across a DST transition still moves the clock by exactly
+// 24 hours. Like time.Time.In, it panics if loc is nil.
+func (fc *fakeClock) SetLocation(loc *time.Location) {
+	fc.l.Lock()
+	defer fc.l.Unlock()
+	fc.loc = loc
+	fc.time = fc.time.In(loc)
+	fc.start = fc.start.In(loc)
+	fc.mono = fc.mono.In(loc)
+}
+
+// Parse parses value with time.ParseInLocation, in the location of the
+// current time of the fakeClock, so that parsed fixtures are in the same zone
+// as Now.
+func (fc *fakeClock) Parse(layout, value string) (time.Time, error) {
+	return time.ParseInLocation(layout, value, fc.Now().Location())
+}
+
+// TruncateToWindow returns the start of the window of the given size which
+// contains t. Windows are aligned on the initial time of the fakeClock, the
+// origin of the simulation, rather than on the zero time like
+// time.Time.Truncate.
+func (fc *fakeClock) TruncateToWindow(t time.Time, window time.Duration) time.Time {
+	return truncateToWindow(fc.start, t, window)
+}
+
+// atLeast calls f then sleeps on c for whatever is left of d.
+func atLeast(c Clock, d time.Duration, f func()) {
+	start := c.Now()
+	f()
+	if remaining := d - c.Since(start); remaining > 0 {
+		c.Sleep(remaining)
+	}
+}
+
+// truncateToWindow rounds t down to a whole number of windows since origin.
+// A non-positive window returns t unchanged.
+func truncateToWindow(origin, t time.Time, window time.Duration) time.Time {
+	if window <= 0 {
+		return t
+	}
+	r := t.Sub(origin) % window
+	if r < 0 {
+		r += window
+	}
+	return t.Add(-r)
+}
+
+// NewTicker returns a Ticker ticking every d on the fakeClock. Like
+// time.NewTicker, it panics if d is not positive, as do NewTickerFunc and
+// NewTickerSize.
+func (fc *fakeClock) NewTicker(d time.Duration) Ticker {
+	return fc.newTicker(d, nil, 1)
+}
+
+// NewTickerSize returns a Ticker whose channel buffers up to buf ticks, so that
+// a test can count every tick of an advance spanning several periods. Each
+// tick due during an advance is sent, as long as the channel has room, and
+// the following ones are dropped. With buf of 0 or 1, it is the same as
+// NewTicker: the ticks due during an advance are coalesced into the last one,
+// which is dropped if a tick is still waiting in the channel.
+func (fc *fakeClock) NewTickerSize(d time.Duration, buf int) Ticker {
+	if buf < 1 {
+		buf = 1
+	}
+	return fc.newTicker(d, nil, buf)
+}
+
+// Tick returns the channel of a new ticker of the fakeClock, like time.Tick.
+// The ticker cannot be stopped, so it stays among the sleepers of the
+// fakeClock for good: Tick is only suitable for clients which tick for their
+// whole lifetime. It returns nil if d <= 0.
+func (fc *fakeClock) Tick(d time.Duration) <-chan time.Time {
+	if d <= 0 {
+		return nil
+	}
+	return fc.NewTicker(d).Chan()
+}
+
+// NewTickerFunc returns a Ticker which calls f in its own goroutine whenever it
+// ticks, as AfterFunc does for timers, rather than sending on its channel,
+// which is nil. Ticks are due and coalesced as with NewTicker, so f is called
+// once per advance at most. Stopping the ticker stops the calls.
+func (fc *fakeClock) NewTickerFunc(d time.Duration, f func()) Ticker {
+	return fc.newTicker(d, f, 0)
+}
+
+func (fc *fakeClock) newTicker(d time.Duration, f func(), buf int) Ticker {
+	if d <= 0 {
+		// Like time.NewTicker: the ticker would fire endlessly.
+		panic("clockwork: non-positive interval for NewTicker")
+	}
+	fc.checkDuration(d)
+	ft := &fakeTicker{
+		stop:   make(chan struct{}),
+		clock:  fc,
+		period: d,
+		fn:     f,
+	}
+	if f == nil {
+		ft.c = make(chan time.Time, buf)
+	}
+	go ft.tick()
+	fc.l.RLock()
+	leakFinalizers := fc.leakFinalizers
+	fc.l.RUnlock()
+	if leakFinalizers {
+		// The finalizer is set on a wrapper since the ticking goroutine keeps
+		// the ticker itself reachable forever.
+		lt := &leakCheckedTicker{ft}
+		runtime.SetFinalizer(lt, func(lt *leakCheckedTicker) {
+			if !lt.isStopped() {
+				fc.recordError(fmt.Errorf("clockwork: ticker with period %v was garbage collected without being stopped", lt.period))
+			}
+		})
+		return lt
+	}
+	return ft
+}
+
+// EnableLeakFinalizers makes the fakeClock record an error, see Errors, when a
+// ticker created afterwards is garbage collected before Stop was called on it.
+// This relies on runtime.SetFinalizer, so leaks are only reported once the
+// garbage collector gets around to the ticker, which may be much later or
+// never: use it to surface leaks, not to assert their absence.
+func (fc *fakeClock) EnableLeakFinalizers() {
+	fc.l.Lock()
+	fc.leakFinalizers = true
+	fc.l.Unlock()
+}
+
+// SetMaxTicksPerAdvance guards against tickers with a tiny period caught in a
+// large Advance. A ticker with more than n ticks due within a single advance
+// delivers n of them at most, as its channel allows, the last one being the
+// most recent, then it is stopped and an error is recorded, see Errors. Zero,
+// the default, means unlimited.
+func (fc *fakeClock) SetMaxTicksPerAdvance(n int) {
+	fc.l.Lock()
+	fc.maxTicksPerAdvance = n
+	fc.l.Unlock()
+}
+
+// Errors returns a copy of the errors recorded by the fakeClock's safety
+// checks, in the order they occurred.
+func (fc *fakeClock) Errors() []error {
+	fc.l.RLock()
+	defer fc.l.RUnlock()
+	return append([]error(nil), fc.errors...)
+}
+
+// SetMaxWaiters makes the fakeClock panic whenever scheduling a sleeper would
+// leave more than n of them pending, to catch a timer leak at the call which
+// leaks rather than at the end of the test. The panic message lists the
+// pending sleepers. Zero, the default, means unlimited.
+func (fc *fakeClock) SetMaxWaiters(n int) {
+	fc.l.Lock()
+	fc.maxWaiters = n
+	fc.l.Unlock()
+}
+
+// checkMaxWaitersLocked panics if having n sleepers pending would exceed the
+// limit set with SetMaxWaiters. The caller must hold the lock.
+func (fc *fakeClock) checkMaxWaitersLocked(n int) {
+	if fc.maxWaiters <= 0 || n <= fc.maxWaiters {
+		return
+	}
+	infos := make([]WaiterInfo, len(fc.sleepers))
+	for i, s := range fc.sleepers.sorted() {
+		infos[i] = s.info()
+	}
+	panic(fmt.Sprintf("clockwork: %d sleepers would be pending, more than the limit of %d; pending: %v", n, fc.maxWaiters, infos))
+}
+
+// SetMinDurationWarning makes the fakeClock record an error, see Errors,
+// whenever a timer, sleep or ticker is scheduled with a positive duration
+// below d, which usually betrays a misconfiguration such as milliseconds
+// passed for seconds. Scheduling itself is not affected. Non-positive
+// durations, which fire right away, are not reported. Zero, the default,
+// turns the warning off.
+func (fc *fakeClock) SetMinDurationWarning(d time.Duration) {
+	fc.l.Lock()
+	fc.minDuration = d
+	fc.l.Unlock()
+}
+
+// checkDuration records an error if d is below the minimum set with
+// SetMinDurationWarning.
+func (fc *fakeClock) checkDuration(d time.Duration) {
+	fc.l.RLock()
+	min := fc.minDuration
+	fc.l.RUnlock()
+	if d > 0 && d < min {
+		fc.recordError(fmt.Errorf("clockwork: scheduled duration %v is below the minimum of %v", d, min))
+	}
+}
+
+func (fc *fakeClock) maxTicks() int {
+	fc.l.RLock()
+	defer fc.l.RUnlock()
+	return fc.maxTicksPerAdvance
+}
+
+func (fc *fakeClock) recordError(err error) {
+	fc.l.Lock()
+	fc.errors = append(fc.errors, err)
+	fc.l.Unlock()
+}
+
+// WaitNextTick blocks until the next tick of any ticker created by the
+// fakeClock, whether or not the tick could be delivered to its channel. It
+// returns the context's error if the context is done first.
+func (fc *fakeClock) WaitNextTick(ctx context.Context) error {
+	fc.l.Lock()
+	if fc.ticked == nil {
+		fc.ticked = make(chan struct{})
+	}
+	ticked := fc.ticked
+	fc.l.Unlock()
+	select {
+	case <-ticked:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// notifyTick releases the callers of WaitNextTick.
+func (fc *fakeClock) notifyTick() {
+	fc.l.Lock()
+	if fc.ticked != nil {
+		close(fc.ticked)
+		fc.ticked = nil
+	}
+	fc.l.Unlock()
+}
+
+// IdleChan returns a channel which is closed the next time the fakeClock is
+// left without any pending sleeper, after an advance or once its last sleeper
+// is stopped, for a driver to wait until all the scheduled work is done. If
+// the fakeClock has no sleeper when IdleChan is called, the channel is already
+// closed. The channel is closed as the last sleepers are removed, possibly
+// before they are notified, and the goroutines they wake up may schedule new
+// sleepers, for which IdleChan must be called again.
+func (fc *fakeClock) IdleChan() <-chan struct{} {
+	fc.l.Lock()
+	defer fc.l.Unlock()
+	if len(fc.sleepers) == 0 {
+		idle := make(chan struct{})
+		close(idle)
+		return idle
+	}
+	if fc.idle == nil {
+		fc.idle = make(chan struct{})
+	}
+	return fc.idle
+}
+
+// notifyIdleLocked releases the callers of IdleChan if fakeClock has no
+// sleeper left. The caller must hold the write lock.
+func (fc *fakeClock) notifyIdleLocked() {
+	if len(fc.sleepers) == 0 && fc.idle != nil {
+		close(fc.idle)
+		fc.idle = nil
+	}
+}
+
+// Advance advances fakeClock to a new point in time, ensuring channels from any
+// previous invocations of After are notified appropriately before returning.
+// Sleepers are notified by expiration; those expiring at the same instant by
+// priority, see NewTimerWithPriority, then in the order they were armed, a
+// timer being armed again by Reset. This order is guaranteed, so that runs
+// are reproducible.
+func (fc *fakeClock) Advance(d time.Duration) {
+	fc.l.Lock()
+	defer fc.unlock()
+	fc.advanceLocked(fc.time.Add(fc.dilateLocked(d)))
+}
+
+// AdvanceAndWait advances fakeClock like Advance, then blocks until the
+// functions of the AfterFunc timers it started have returned, so that a test
+// can assert on their side effects right away. The functions started by these
+// functions, e.g. those of AfterFunc timers they create with a non-positive
+// duration, are waited for as well. So are the functions started concurrently
+// by other advances while it waits, which AdvanceAndWait cannot tell apart. It
+// does not wait for the functions of tickers created with NewTickerFunc.
+func (fc *fakeClock) AdvanceAndWait(d time.Duration) {
+	t := &funcTracker{}
+	t.cond = sync.NewCond(&t.mu)
+	fc.l.Lock()
+	fc.trackers = append(fc.trackers, t)
+	fc.advanceLocked(fc.time.Add(fc.dilateLocked(d)))
+	fc.unlock()
+	t.wait()
+	fc.l.Lock()
+	trackers := make([]*funcTracker, 0, len(fc.trackers)-1)
+	for _, other := range fc.trackers {
+		if other != t {
+			trackers = append(trackers, other)
+		}
+	}
+	// A new slice, since running functions hold on to the previous one.
+	fc.trackers = trackers
+	fc.l.Unlock()
+}
+
+// funcTracker counts running functions of AfterFunc timers, see
+// AdvanceAndWait.
+type funcTracker struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	running int
+}
+
+func (t *funcTracker) add(delta int) {
+	t.mu.Lock()
+	t.running += delta
+	if t.running == 0 {
+		t.cond.Broadcast()
+	}
+	t.mu.Unlock()
+}
+
+// wait blocks until no function counted by t is running.
+func (t *funcTracker) wait() {
+	t.mu.Lock()
+	for t.running > 0 {
+		t.cond.Wait()
+	}
+	t.mu.Unlock()
+}
+
+// AdvanceRandom advances fakeClock by a pseudo-random duration in [min, max]
+// and returns it, for fuzz-style tests stepping the clock irregularly. The
+// durations are drawn from a generator created with seed the first time, then
+// reused by the following calls with the same seed, so that a sequence of
+// calls is reproducible from its seed; a different seed starts a new
+// generator. AdvanceRandom is not affected by SetDilation. It panics if min is
+// greater than max.
+func (fc *fakeClock) AdvanceRandom(min, max time.Duration, seed int64) time.Duration {
+	if min > max {
+		panic(fmt.Sprintf("clockwork: AdvanceRandom called with min %v greater than max %v", min, max))
+	}
+	fc.l.Lock()
+	defer fc.unlock()
+	if fc.random == nil || fc.randomSeed != seed {
+		fc.random = rand.New(rand.NewSource(seed))
+		fc.randomSeed = seed
+	}
+	d := min
+	if span := int64(max-min) + 1; span > 0 {
+		d += time.Duration(fc.random.Int63n(span))
+	} else {
+		// The span overflows, any non-negative offset is in range.
+		d += time.Duration(fc.random.Int63())
+	}
+	fc.advanceLocked(fc.time.Add(d))
+	return d
+}
+
+// SetDilation makes time slow down, or speed up, around target: from then on,
+// Advance(d) and AdvanceChecked(d) move fakeClock by d*factor(remaining),
+// remaining being the time left until target before the advance, negative
+// once target is passed. The scaled duration is truncated to a whole number of
+// nanoseconds. Sleepers still expire at the absolute time they were scheduled
+// for, so dilation only changes how many advances it takes to reach them; the
+// other ways of moving the clock, such as ReplayTimeline or FireOnly, are not
+// dilated. Passing a nil factor removes the dilation.
+func (fc *fakeClock) SetDilation(target time.Time, factor func(remaining time.Duration) float64) {
+	fc.l.Lock()
+	fc.dilationTarget = target
+	fc.dilation = factor
+	fc.l.Unlock()
+}
+
+// dilateLocked returns the duration an advance by d actually moves fakeClock
+// by, see SetDilation. The caller must hold the lock.
+func (fc *fakeClock) dilateLocked(d time.Duration) time.Duration {
+	if fc.dilation == nil || d == 0 {
+		return d
+	}
+	return time.Duration(float64(d) * fc.dilation(fc.dilationTarget.Sub(fc.time)))
+}
+
+// AdvanceTo advances fakeClock to the absolute time t, notifying the sleepers
+// expiring at or before t like Advance does, which spares tests targeting a
+// known instant computing the duration to it. Advancing to the current time
+// only notifies the sleepers already due. AdvanceTo is not affected by
+// SetDilation. It panics if t is before the current time: moving back past
+// pending sleepers has no sensible meaning.
+func (fc *fakeClock) AdvanceTo(t time.Time) {
+	fc.l.Lock()
+	defer fc.unlock()
+	if t.Before(fc.time) {
+		panic(fmt.Sprintf("clockwork: cannot advance to %v, before the current time %v", t, fc.time))
+	}
+	fc.advanceLocked(t)
+}
+
+// SetTime moves fakeClock to t, forward or backward, without notifying any
+// sleeper, as when the host clock is stepped by NTP. Unlike AdvanceTo, the
+// sleepers expiring by t stay pending until the next advance, which notifies
+// them even if it is by zero; Since, Until and the following advances are
+// relative to t right away. The monotonic reading of the clock, see
+// MonotonicSince, is not affected.
+func (fc *fakeClock) SetTime(t time.Time) {
+	fc.l.Lock()
+	fc.time = fc.inLocation(t)
+	fc.unlock()
+}
+
+// Rewind moves fakeClock back by d, as when a leap second is removed or NTP
+// makes a bad correction, to stress code which assumes time never goes back.
+// No sleeper is notified or reordered: the sleepers keep their absolute
+// expiration, so they simply wait d longer. Since can then return negative
+// durations for times taken before the rewind. Like SetTime, it leaves the
+// monotonic reading of the clock alone and does not call the OnAdvance hooks.
+// It panics if d is negative.
+func (fc *fakeClock) Rewind(d time.Duration) {
+	if d < 0 {
+		panic(fmt.Sprintf("clockwork: Rewind called with a negative duration %v", d))
+	}
+	fc.l.Lock()
+	fc.moveToLocked(fc.time.Add(-d))
+	fc.unlock()
+}
+
+// AdvanceToNextTimer advances fakeClock to the earliest expiration of its
+// sleepers and no further, notifying every sleeper expiring at that instant,
+// and returns how far the clock moved. This single-steps through a sequence of
+// timers whose durations the test doesn't know. With a bias set with
+// SetFireBias, the clock moves to the biased expiration. When no sleeper is
+// pending, it does nothing and returns 0; when the earliest sleeper is
+// overdue, after SetTime, it is notified without moving the clock.
+func (fc *fakeClock) AdvanceToNextTimer() time.Duration {
+	fc.l.Lock()
+	defer fc.unlock()
+	next, ok := fc.nextExpirationLocked()
+	if !ok {
+		return 0
+	}
+	start := fc.time
+	next = next.Add(fc.fireBias)
+	if next.Before(start) {
+		next = start
+	}
+	fc.advanceLocked(next)
+	return next.Sub(start)
+}
+
+// AdvanceChecked advances fakeClock like Advance, then checks whether the
+// advance drained every sleeper while callers of BlockUntil, BlockUntilSleepers
+// or BlockUntilAtMost are still waiting: unless another goroutine schedules
+// more sleepers, those callers are deadlocked, and an error listing what they
+// wait for is returned.
+// Only the state right after the advance is checked; a deadlock caused by
+// goroutines which later fail to schedule anything goes unnoticed.
+func (fc *fakeClock) AdvanceChecked(d time.Duration) error {
+	fc.l.Lock()
+	defer fc.unlock()
+	fc.advanceLocked(fc.time.Add(fc.dilateLocked(d)))
+	if len(fc.sleepers) > 0 || !fc.hasBlockersLocked() {
+		return nil
+	}
+	var waits []string
+	for _, b := range fc.blockers {
+		waits = append(waits, fmt.Sprintf("BlockUntil(%d)", b.count))
+	}
+	for _, b := range fc.sleepBlockers {
+		waits = append(waits, fmt.Sprintf("BlockUntilSleepers(%d)", b.count))
+	}
+	for _, b := range fc.drainBlockers {
+		waits = append(waits, fmt.Sprintf("BlockUntilAtMost(%d)", b.count))
+	}
+	return fmt.Errorf("clockwork: advance left no sleeper while waiting in %s", strings.Join(waits, ", "))
+}
+
+// AdvanceToMedian advances fakeClock to the expiration of its median sleeper,
+// the one at index (n-1)/2 of the n sleepers sorted by expiration, so that
+// about half of the pending work is done. Every sleeper expiring up to that
+// instant is notified and their number is returned. With a single sleeper the
+// clock advances to its expiration; with none it doesn't move and 0 is
+// returned. With a bias set with SetFireBias, the clock moves to the biased
+// expiration of the median sleeper, so that it still fires.
+func (fc *fakeClock) AdvanceToMedian() int {
+	fc.l.Lock()
+	defer fc.unlock()
+	if len(fc.sleepers) == 0 {
+		return 0
+	}
+	expirations := make([]time.Time, len(fc.sleepers))
+	for i, s := range fc.sleepers {
+		expirations[i] = s.until
+	}
+	sort.Slice(expirations, func(i, j int) bool { return expirations[i].Before(expirations[j]) })
+	median := expirations[(len(expirations)-1)/2].Add(fc.fireBias)
+	if median.Before(fc.time) {
+		median = fc.time
+	}
+	return fc.advanceLocked(median)
+}
+
+// DriveUntilQuiescent runs the simulation to completion: it advances fakeClock
+// to the expiration of its earliest sleeper, lets the goroutines woken up
+// settle, and starts over until there are no sleepers left, in which case it
+// returns nil, or until the context is done, in which case it returns the
+// context's error. Tickers never let the clock become quiescent, so the
+// context should carry a deadline, e.g. derived from the test's one.
+func (fc *fakeClock) DriveUntilQuiescent(ctx context.Context) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		fc.l.Lock()
+		next, ok := fc.nextExpirationLocked()
+		if ok {
+			next = next.Add(fc.fireBias)
+			if next.Before(fc.time) {
+				next = fc.time
+			}
+			fc.advanceLocked(next)
+		}
+		fc.unlock()
+		if n := fc.settle(); !ok && n == 0 {
+			return nil
+		}
+	}
+}
+
+// settle gives the goroutines woken up by an advance a chance to run and
+// schedule their next sleepers, and returns the number of sleepers once it has
+// been stable for a few rounds. This is a best effort: a goroutine which takes
+// long enough before scheduling its sleeper is missed.
+func (fc *fakeClock) settle() int {
+	const stableRounds = 3
+	n := -1
+	for stable := 0; stable < stableRounds; {
+		runtime.Gosched()
+		time.Sleep(100 * time.Microsecond)
+		fc.l.RLock()
+		current := len(fc.sleepers)
+		fc.l.RUnlock()
+		if current == n {
+			stable++
+		} else {
+			n, stable = current, 0
+		}
+	}
+	return n
+}
+
+// ReplayTimeline advances fakeClock to each of the given instants in turn, as
+// when replaying recorded trace data. The instants must not decrease and must
+// not be before the current time; the timeline is rejected up front otherwise.
+func (fc *fakeClock) ReplayTimeline(times []time.Time) error {
+	for i := 1; i < len(times); i++ {
+		if times[i].Before(times[i-1]) {
+			return fmt.Errorf("clockwork: timeline is not monotonic: %v at index %d is before %v", times[i], i, times[i-1])
+		}
+	}
+	for i, t := range times {
+		fc.l.Lock()
+		if t.Before(fc.time) {
+			fc.l.Unlock()
+			return fmt.Errorf("clockwork: timeline instant %v at index %d is before the current time %v", t, i, fc.time)
+		}
+		fc.advanceLocked(t)
+		fc.unlock()
+	}
+	return nil
+}
+
+// advanceLocked moves fakeClock to end, notifying the sleepers which expire
+// on the way, and returns how many were notified. The caller must hold the
+// write lock.
+func (fc *fakeClock) advanceLocked(end time.Time) int {
+	end = fc.inLocation(end)
+	start := time.Now()
+	defer func() { fc.realTimeInAdvance += time.Since(start) }()
+	if hooks := fc.advanceHooks; len(hooks) > 0 {
+		from := fc.time
+		defer func() {
+			fc.syncFuncs = append(fc.syncFuncs, func() {
+				for _, hook := range hooks {
+					hook(from, end)
+				}
+			})
+		}()
+	}
+	var due []*sleeper
+	for len(fc.sleepers) > 0 && end.Sub(fc.sleepers[0].until.Add(fc.fireBias)) >= 0 {
+		due = append(due, heap.Pop(&fc.sleepers).(*sleeper))
+	}
+	fc.blockers = notifyBlockers(fc.blockers, len(fc.sleepers))
+	fc.notifySleepBlockersLocked()
+	fc.notifyDrainBlockersLocked()
+	fc.notifyIdleLocked()
+	fc.moveToLocked(end)
+	// Notify by expiration, then by priority, then in arming order. The
+	// heap already popped the sleepers by expiration then arming order, only
+	// the rarely set priorities may call for sorting them.
+	for _, s := range due {
+		if s.priority != 0 {
+			sort.SliceStable(due, func(i, j int) bool {
+				if !due[i].until.Equal(due[j].until) {
+					return due[i].until.Before(due[j].until)
+				}
+				return due[i].priority > due[j].priority
+			})
+			break
+		}
+	}
+	var deliver []bool
+	if sink := fc.deliverySink; sink != nil && len(due) > 0 {
+		deliver = make([]bool, len(due))
+		infos := make([]WaiterInfo, len(due))
+		for i, s := range due {
+			infos[i] = s.info()
+		}
+		fc.whileUnlocked(func() {
+			for i := range due {
+				deliver[i] = sink(infos[i], end)
+			}
+		})
+	}
+	fired := 0
+	for i, s := range due {
+		if n := fc.herdBatchSize; n > 0 && i > 0 && i%n == 0 {
+			// Let other users of the clock in between two batches.
+			fc.whileUnlocked(func() {})
+		}
+		if s.pendingOn(fc) {
+			// Reset while the lock was released, e.g. by the sink: it
+			// waits for its new expiration.
+			continue
+		}
+		if deliver != nil && !deliver[i] {
+			// The sink took over the delivery, only expire the sleeper.
+			if atomic.CompareAndSwapUint32(&s.done, 0, 1) {
+				fc.forgetDedupLocked(s)
+				fired++
+			}
+			continue
+		}
+		if fc.fireLocked(s, end) {
+			fired++
+			if fc.yieldAfterFire {
+				fc.whileUnlocked(runtime.Gosched)
+			}
+		}
+	}
+	return fired
+}
+
+// OnAdvance registers f to be called at the end of each advance of the
+// fakeClock, with the time before and after it: Advance and AdvanceChecked,
+// even by zero, as well as AdvanceTo, AdvanceRandom, AdvanceToMedian,
+// AdvanceToNextTimer, each step of ReplayTimeline and DriveUntilQuiescent and
+// the jumps made with SetAutoAdvance.
+// SetTime and FireOnly, which are not advances, do not call it. The hooks are
+// called in registration order by the goroutine advancing the clock, once the
+// sleepers of the advance have been notified and the lock of the clock
+// released, so they may use the clock, and before the advance returns.
+// Concurrent advances each call the hooks for their own move. There is no way
+// to unregister a hook.
+func (fc *fakeClock) OnAdvance(f func(from, to time.Time)) {
+	fc.l.Lock()
+	fc.advanceHooks = append(fc.advanceHooks, f)
+	fc.l.Unlock()
+}
+
+// SetDeliverySink installs a sink which sees every sleeper expiring during an
+// advance of the fakeClock, along with the time of the advance, before it is
+// notified. When the sink returns false, the sleeper expires without its
+// usual notification: nothing is sent on its channel and its function is not
+// called. The sink is called in notification order, without holding the lock of
+// the clock so that it may use the clock, once for each expiring sleeper and
+// before any of them is notified. A sleeper which the sink, or another
+// goroutine meanwhile, stops is not notified; one which is reset waits for its
+// new expiration instead. Sleepers expiring outside of an advance, such as
+// timers created with a non-positive duration, bypass the sink. Passing nil
+// removes the sink.
+func (fc *fakeClock) SetDeliverySink(sink func(e WaiterInfo, at time.Time) bool) {
+	fc.l.Lock()
+	fc.deliverySink = sink
+	fc.l.Unlock()
+}
+
+// fireLocked notifies s with the given time, unless it was stopped, and
+// reports whether it did. The caller must hold the write lock.
+func (fc *fakeClock) fireLocked(s *sleeper, now time.Time) bool {
+	if !s.awaken(now) {
+		return false
+	}
+	fc.forgetDedupLocked(s)
+	if fc.fireLog != nil {
+		if s.name != "" {
+			fmt.Fprintf(fc.fireLog, "%s %s %q\n", now.Format(time.RFC3339Nano), s.kind, s.name)
+		} else {
+			fmt.Fprintf(fc.fireLog, "%s %s\n", now.Format(time.RFC3339Nano), s.kind)
+		}
+	}
+	return true
+}
+
+// RecordFires writes a line to w for each sleeper the fakeClock notifies from
+// now on, as it notifies it: the time it was notified with, formatted as
+// RFC3339Nano, its kind and, for a timer of AfterFuncNamed, its quoted name.
+// The output is meant to be compared against a golden file with CompareFires.
+// Writes happen while the clock is locked, so w must not use the clock.
+// Passing nil stops the recording.
+func (fc *fakeClock) RecordFires(w io.Writer) {
+	fc.l.Lock()
+	fc.fireLog = w
+	fc.l.Unlock()
+}
+
+// SetHerdBatchSize makes each advance of the fakeClock notify its expired
+// sleepers n at a time, releasing the lock of the clock between two batches so
+// that concurrent users are not held up by a large simultaneous expiry. The
+// time of the clock is already the end of the advance when the first batch is
+// notified, and all the sleepers of the advance are notified with that same
+// time. The advance itself does not move the clock between batches, but other
+// goroutines may: a concurrent advance moves it further, and a sleeper
+// stopped or reset in between is not notified by this advance. Zero, the
+// default, notifies all of them at once.
+func (fc *fakeClock) SetHerdBatchSize(n int) {
+	fc.l.Lock()
+	fc.herdBatchSize = n
+	fc.l.Unlock()
+}
+
+// SetFireBias models a platform whose timers consistently fire late, for a
+// positive bias, or early, for a negative one: from then on, an advance of the
+// fakeClock notifies a sleeper expiring at e once the clock reaches e+bias.
+// As usual, the sleeper is notified with the time the clock is advanced to,
+// not its nominal expiration. The bias only applies to advances: a sleeper
+// scheduled with a non-positive duration still fires right away, and
+// expirations reported by the clock, as by State or Waiters, are nominal. The
+// helpers computing how far to advance, such as AdvanceToNextTimer,
+// AdvanceToMedian and TimeToDrain, account for the bias.
+func (fc *fakeClock) SetFireBias(bias time.Duration) {
+	fc.l.Lock()
+	fc.fireBias = bias
+	fc.l.Unlock()
+}
+
+// SetAutoAdvance turns on the auto-advance mode of the fakeClock, for tests
+// whose background goroutines sleep or wait on timers and would be fragile to
+// pump with Advance: whenever a sleeper is scheduled while no other one is
+// pending, the clock immediately advances to its expiration, provided it is at
+// most step ahead, notifying it and any ticker due on the way. Sleepers further
+// ahead are left for an explicit advance. The clock never advances on its own
+// past the next expiration, so the simulation stays deterministic, and the
+// pending ticks of tickers neither trigger an auto-advance nor prevent one,
+// since a ticker would otherwise drive the clock forever. SetAutoAdvance(0)
+// turns the mode off.
+func (fc *fakeClock) SetAutoAdvance(step time.Duration) {
+	fc.l.Lock()
+	fc.autoAdvance = step
+	fc.l.Unlock()
+}
+
+// autoAdvanceLocked advances fakeClock to the expiration of s, which was just
+// scheduled, if the auto-advance mode calls for it. The caller must hold the
+// write lock.
+func (fc *fakeClock) autoAdvanceLocked(s *sleeper) {
+	if fc.autoAdvance <= 0 || s.kind == KindTicker || s.until.Sub(fc.time) > fc.autoAdvance {
+		return
+	}
+	for _, other := range fc.sleepers {
+		if other != s && other.kind != KindTicker {
+			return
+		}
+	}
+	fc.advanceLocked(s.until)
+}
+
+// SetAfterFuncTimeout protects the advances of the fakeClock from the functions
+// of AfterFuncSync timers which block: once set, each function runs in a
+// goroutine of its own, which the advance waits for at most d of real time.
+// If the function has not returned by then, the advance records an error, see
+// Errors, and moves on to the next function, leaving it running. The functions
+// which return in time still have returned by the time the advance returns,
+// but they no longer run in the advancing goroutine. A d of 0, the default,
+// makes advances wait for the functions however long they take, in the
+// advancing goroutine. The functions of AfterFunc timers, which never hold up
+// an advance, are not affected.
+func (fc *fakeClock) SetAfterFuncTimeout(d time.Duration) {
+	fc.l.Lock()
+	fc.afterFuncTimeout = d
+	fc.l.Unlock()
+}
+
+// withWatchdog returns a function calling f in a goroutine of its own and
+// waiting for it for at most timeout, see SetAfterFuncTimeout.
+func (fc *fakeClock) withWatchdog(f func(), timeout time.Duration) func() {
+	return func() {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			f()
+		}()
+		watchdog := time.NewTimer(timeout)
+		defer watchdog.Stop()
+		select {
+		case <-done:
+		case <-watchdog.C:
+			fc.recordError(fmt.Errorf("clockwork: AfterFuncSync function still running after %v, the advance went on without it", timeout))
+		}
+	}
+}
+
+// SetYieldAfterFire makes each advance of the fakeClock release its lock and
+// call runtime.Gosched after notifying a sleeper, giving the goroutine it woke
+// up a chance to run before the advance goes on and returns. This cheaply
+// reduces the flakiness of tests asserting on the effects of a timer right
+// after advancing, but it is only best effort: the scheduler may still run the
+// woken goroutine later. BlockUntil, or DriveUntilQuiescent which waits for
+// the woken goroutines to settle, are the reliable options. A sleeper of the
+// advance which a woken goroutine stops or resets before its turn is not
+// notified by the advance.
+func (fc *fakeClock) SetYieldAfterFire(enabled bool) {
+	fc.l.Lock()
+	fc.yieldAfterFire = enabled
+	fc.l.Unlock()
+}
+
+// RealTimeInAdvance returns how much real time, as measured by the time
+// package rather than the fakeClock, has been spent notifying sleepers while
+// advancing the fakeClock. A large value points at slow sleeper callbacks.
+func (fc *fakeClock) RealTimeInAdvance() time.Duration {
+	fc.l.RLock()
+	defer fc.l.RUnlock()
+	return fc.realTimeInAdvance
+}
+
+// HasBlockers reports whether any caller of BlockUntil, BlockUntilSleepers or
+// BlockUntilAtMost is still waiting for its number of sleepers.
+func (fc *fakeClock) HasBlockers() bool {
+	fc.l.RLock()
+	defer fc.l.RUnlock()
+	return fc.hasBlockersLocked()
+}
+
+func (fc *fakeClock) hasBlockersLocked() bool {
+	return len(fc.blockers)+len(fc.sleepBlockers)+len(fc.drainBlockers) > 0
+}
+
+// Dimension returns the time axis of fakeClock with the given name, creating
+// it on first use, for simulations with several orthogonal notions of time
+// such as simulated time and CPU time. A dimension is a FakeClock of its own:
+// it starts at the current time of fakeClock but then shares nothing with it
+// nor with the other dimensions. Its sleepers only fire when it is advanced,
+// and advancing fakeClock or another dimension does not move it.
+func (fc *fakeClock) Dimension(name string) FakeClock {
+	return fc.dimension(name)
+}
+
+func (fc *fakeClock) dimension(name string) *fakeClock {
+	fc.l.Lock()
+	defer fc.l.Unlock()
+	if fc.dimensions == nil {
+		fc.dimensions = make(map[string]*fakeClock)
+	}
+	dim, ok := fc.dimensions[name]
+	if !ok {
+		dim = &fakeClock{time: fc.time, start: fc.time, mono: fc.time, loc: fc.loc}
+		fc.dimensions[name] = dim
+	}
+	return dim
+}
+
+// AdvanceDimension advances the named dimension of fakeClock by d, see
+// Dimension.
+func (fc *fakeClock) AdvanceDimension(name string, d time.Duration) {
+	fc.dimension(name).Advance(d)
+}
+
+// NewTimerInDimension creates a new Timer in the named dimension of fakeClock,
+// which fires once that dimension advances by d, see Dimension.
+func (fc *fakeClock) NewTimerInDimension(name string, d time.Duration) Timer {
+	return fc.dimension(name).NewTimer(d)
+}
+
+// WaiterCount returns the number of sleepers currently pending on the
+// fakeClock, the number BlockUntil waits for, without blocking: for instance
+// to check that no timer is left at the end of a test, or to find out why
+// BlockUntil hangs.
+func (fc *fakeClock) WaiterCount() int {
+	fc.l.RLock()
+	defer fc.l.RUnlock()
+	return len(fc.sleepers)
+}
+
+// BlockUntil will block until the fakeClock has the given number of sleepers
+// (callers of Sleep or After)
+func (fc *fakeClock) BlockUntil(n int) {
+	fc.l.Lock()
+	// Fast path: current number of sleepers is what we're looking for
+	if len(fc.sleepers) == n {
+		fc.l.Unlock()
+		return
+	}
+	// Otherwise, set up a new blocker
+	b := &blocker{
+		count: n,
+		ch:    make(chan struct{}),
+	}
+	fc.blockers = append(fc.blockers, b)
+	fc.l.Unlock()
+	<-b.ch
+}
+
+// BlockUntilSleepers blocks until exactly n goroutines are waiting in Sleep on
+// the fakeClock, ignoring the other sleepers such as timers and tickers, so
+// that a test can wait for the code under test to be sleeping. It returns the
+// context's error if the context is done first.
+func (fc *fakeClock) BlockUntilSleepers(ctx context.Context, n int) error {
+	fc.l.Lock()
+	if fc.countSleepsLocked() == n {
+		fc.l.Unlock()
+		return nil
+	}
+	b := &blocker{
+		count: n,
+		ch:    make(chan struct{}),
+	}
+	fc.sleepBlockers = append(fc.sleepBlockers, b)
+	fc.l.Unlock()
+	select {
+	case <-b.ch:
+		return nil
+	case <-ctx.Done():
+		fc.l.Lock()
+		for i, other := range fc.sleepBlockers {
+			if other == b {
+				fc.sleepBlockers = append(fc.sleepBlockers[:i:i], fc.sleepBlockers[i+1:]...)
+				break
+			}
+		}
+		fc.l.Unlock()
+		return ctx.Err()
+	}
+}
+
+// BlockUntilAtMost blocks until the fakeClock has n sleepers or fewer, to wait
+// for pending work to drain, where BlockUntil waits for it to be scheduled. It
+// returns nil right away if there are already few enough sleepers, and the
+// error of the context if it is done first.
+func (fc *fakeClock) BlockUntilAtMost(ctx context.Context, n int) error {
+	fc.l.Lock()
+	if len(fc.sleepers) <= n {
+		fc.l.Unlock()
+		return nil
+	}
+	b := &blocker{
+		count: n,
+		ch:    make(chan struct{}),
+	}
+	fc.drainBlockers = append(fc.drainBlockers, b)
+	fc.l.Unlock()
+	select {
+	case <-b.ch:
+		return nil
+	case <-ctx.Done():
+		fc.l.Lock()
+		for i, other := range fc.drainBlockers {
+			if other == b {
+				fc.drainBlockers = append(fc.drainBlockers[:i:i], fc.drainBlockers[i+1:]...)
+				break
+			}
+		}
+		fc.l.Unlock()
+		return ctx.Err()
+	}
+}
+
+// notifyDrainBlockersLocked releases the callers of BlockUntilAtMost waiting
+// for at most the current number of sleepers. The caller must hold the write
+// lock.
+func (fc *fakeClock) notifyDrainBlockersLocked() {
+	if len(fc.drainBlockers) == 0 {
+		return
+	}
+	var waiting []*blocker
+	for _, b := range fc.drainBlockers {
+		if len(fc.sleepers) <= b.count {
+			close(b.ch)
+		} else {
+			waiting = append(waiting, b)
+		}
+	}
+	fc.drainBlockers = waiting
+}
+
+// countSleepsLocked returns the number of sleepers of kind KindSleep. The
+// caller must hold the lock.
+func (fc *fakeClock) countSleepsLocked() int {
+	n := 0
+	for _, s := range fc.sleepers {
+		if s.kind == KindSleep {
+			n++
+		}
+	}
+	return n
+}
+
+// notifySleepBlockersLocked releases the callers of BlockUntilSleepers waiting
+// for the current number of sleeping goroutines. The caller must hold the
+// write lock.
+func (fc *fakeClock) notifySleepBlockersLocked() {
+	if len(fc.sleepBlockers) > 0 {
+		fc.sleepBlockers = notifyBlockers(fc.sleepBlockers, fc.countSleepsLocked())
 	}
-	fc.blockers = append(fc.blockers, b)
-	fc.l.Unlock()
-	<-b.ch
 }
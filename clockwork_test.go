@@ -1,7 +1,14 @@
 package clockwork
 
 import (
+	"context"
+	"expvar"
+	"fmt"
 	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -268,3 +275,2154 @@ func TestLocation(t *testing.T) {
 	fc := NewFakeClockAt(time.Date(2020, 01, 16, 23, 12, 12, 0, loc))
 	assert.Equal(t, loc, fc.Location())
 }
+
+func TestFakeClockSetLocation(t *testing.T) {
+	loc := time.FixedZone("local", -3600)
+	start := time.Date(2020, 1, 16, 23, 12, 12, 0, time.UTC)
+	fc := NewFakeClockAtInLocation(start, loc)
+	if now := fc.Now(); !now.Equal(start) || now.Location() != loc {
+		t.Fatalf("got %v, want %v in %v", now, start, loc)
+	}
+
+	timer := fc.NewTimer(time.Hour)
+	fc.SetLocation(time.UTC)
+	if now := fc.Now(); !now.Equal(start) || now.Location() != time.UTC {
+		t.Fatalf("got %v after SetLocation, want %v", now, start)
+	}
+	fc.Advance(time.Hour)
+	if fired := <-timer.C(); !fired.Equal(start.Add(time.Hour)) || fired.Location() != time.UTC {
+		t.Errorf("timer fired with %v, want %v", fired, start.Add(time.Hour))
+	}
+
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no time zone database: %v", err)
+	}
+	// the day before the switch to daylight saving time in 2021
+	fc = NewFakeClockAtInLocation(time.Date(2021, 3, 13, 12, 0, 0, 0, ny), ny)
+	before := fc.Now()
+	fc.Advance(24 * time.Hour)
+	if got := fc.Now().Sub(before); got != 24*time.Hour {
+		t.Errorf("advanced by %v across the DST transition, want %v", got, 24*time.Hour)
+	}
+	if got := fc.Now().Hour(); got != 13 {
+		t.Errorf("got hour %d after the DST transition, want 13", got)
+	}
+}
+
+func TestFakeClockSetLocationKept(t *testing.T) {
+	loc := time.FixedZone("local", -3600)
+	start := time.Date(2020, 1, 16, 23, 12, 12, 0, time.UTC)
+	for _, tc := range []struct {
+		name string
+		move func(fc FakeClock) time.Time
+	}{
+		{"AdvanceTo", func(fc FakeClock) time.Time {
+			fc.AdvanceTo(start.Add(time.Hour))
+			return start.Add(time.Hour)
+		}},
+		{"SetTime", func(fc FakeClock) time.Time {
+			fc.SetTime(start.Add(-time.Hour))
+			return start.Add(-time.Hour)
+		}},
+		{"Reset", func(fc FakeClock) time.Time {
+			fc.Reset(start.Add(time.Hour))
+			return start.Add(time.Hour)
+		}},
+		{"AdvanceToNextTimer", func(fc FakeClock) time.Time {
+			fc.SetLocation(time.UTC)
+			timer := fc.NewTimer(time.Minute)
+			fc.SetLocation(loc)
+			fc.AdvanceToNextTimer()
+			if fired := <-timer.C(); fired.Location() != loc {
+				t.Errorf("timer fired in %v, want %v", fired.Location(), loc)
+			}
+			return start.Add(time.Minute)
+		}},
+		{"FireOnly", func(fc FakeClock) time.Time {
+			fc.SetLocation(time.UTC)
+			timer := fc.NewTimer(time.Minute)
+			fc.SetLocation(loc)
+			if err := fc.FireOnly(timer); err != nil {
+				t.Fatal(err)
+			}
+			return start.Add(time.Minute)
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fc := NewFakeClockAtInLocation(start, loc)
+			want := tc.move(fc)
+			if now := fc.Now(); !now.Equal(want) || now.Location() != loc {
+				t.Errorf("got %v, want %v in %v", now, want, loc)
+			}
+		})
+	}
+}
+
+func TestFakeClockNowBytes(t *testing.T) {
+	fc := NewFakeClockAt(time.Date(1999, time.February, 3, 4, 5, 6, 7, time.UTC))
+	buf := make([]byte, 0, 64)
+	buf = append(buf, "ts="...)
+	got := string(fc.NowBytes(buf))
+	want := "ts=1999-02-03T04:05:06.000000007Z"
+	if got != want {
+		t.Fatalf("fakeClock.NowBytes() returned unexpected value: want=%q, got=%q", want, got)
+	}
+}
+
+func BenchmarkFakeClockNowBytes(b *testing.B) {
+	fc := NewFakeClock()
+	buf := make([]byte, 0, 64)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf = fc.NowBytes(buf[:0])
+	}
+}
+
+func BenchmarkFakeClockNowFormat(b *testing.B) {
+	fc := NewFakeClock()
+	var buf []byte
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf = append(buf[:0], fc.Now().Format(time.RFC3339Nano)...)
+	}
+}
+
+// newFakeClockWithWaiters returns a fakeClock with n timers pending far in the
+// future.
+func newFakeClockWithWaiters(n int) FakeClock {
+	fc := NewFakeClock()
+	for i := 0; i < n; i++ {
+		fc.NewTimer(time.Duration(n+i) * time.Hour)
+	}
+	return fc
+}
+
+func BenchmarkFakeClockAdvanceManyWaiters(b *testing.B) {
+	fc := newFakeClockWithWaiters(10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fc.NewTimer(time.Second)
+		fc.Advance(time.Second)
+	}
+}
+
+func BenchmarkFakeClockAdvanceSingleWaiter(b *testing.B) {
+	fc := NewFakeClock()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		fc.NewTimer(time.Second)
+		fc.Advance(time.Second)
+	}
+}
+
+func BenchmarkFakeClockStopManyWaiters(b *testing.B) {
+	fc := newFakeClockWithWaiters(10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fc.NewTimer(time.Second).Stop()
+	}
+}
+
+func TestFakeClockUnarmedTimer(t *testing.T) {
+	fc := &fakeClock{}
+	timer := fc.NewUnarmedTimer()
+	if timer.Stop() {
+		t.Errorf("unarmed timer could be stopped")
+	}
+	fc.BlockUntil(0)
+	fc.Advance(time.Hour)
+	select {
+	case <-timer.C():
+		t.Errorf("unarmed timer did emit time")
+	default:
+	}
+	if timer.Reset(5) {
+		t.Errorf("arming an unarmed timer didn't return false")
+	}
+	fc.BlockUntil(1)
+	fc.Advance(4)
+	select {
+	case <-timer.C():
+		t.Errorf("armed timer emitted time prematurely")
+	default:
+	}
+	fc.Advance(1)
+	select {
+	case <-timer.C():
+	default:
+		t.Errorf("armed timer didn't emit time")
+	}
+	fc.BlockUntil(0)
+}
+
+func TestFakeClockReplayTimeline(t *testing.T) {
+	fc := NewFakeClock()
+	start := fc.Now()
+	one := fc.After(time.Second)
+	five := fc.After(5 * time.Second)
+	times := []time.Time{start.Add(time.Second), start.Add(3 * time.Second), start.Add(3 * time.Second)}
+	if err := fc.ReplayTimeline(times); err != nil {
+		t.Fatalf("unexpected error replaying timeline: %v", err)
+	}
+	if got := <-one; !got.Equal(start.Add(time.Second)) {
+		t.Errorf("one fired at the wrong time, got: %v, want: %v", got, start.Add(time.Second))
+	}
+	select {
+	case <-five:
+		t.Errorf("five returned prematurely!")
+	default:
+	}
+	if now := fc.Now(); !now.Equal(start.Add(3 * time.Second)) {
+		t.Errorf("clock at the wrong time after replay, got: %v, want: %v", now, start.Add(3*time.Second))
+	}
+
+	bad := []time.Time{start.Add(10 * time.Second), start.Add(6 * time.Second)}
+	if err := fc.ReplayTimeline(bad); err == nil {
+		t.Errorf("non-monotonic timeline was accepted")
+	}
+	if err := fc.ReplayTimeline([]time.Time{start}); err == nil {
+		t.Errorf("timeline starting in the past was accepted")
+	}
+	select {
+	case <-five:
+		t.Errorf("rejected timeline advanced the clock")
+	default:
+	}
+}
+
+func TestFakeClockState(t *testing.T) {
+	fc := NewFakeClock()
+	start := fc.Now()
+	now, _, hasNext := fc.State()
+	if !now.Equal(start) || hasNext {
+		t.Fatalf("unexpected state for an idle clock: now=%v hasNext=%v", now, hasNext)
+	}
+	fc.NewTimer(5 * time.Second)
+	fc.NewTimer(2 * time.Second)
+	fc.Advance(time.Second)
+	now, next, hasNext := fc.State()
+	if !now.Equal(start.Add(time.Second)) {
+		t.Errorf("wrong current time, got: %v, want: %v", now, start.Add(time.Second))
+	}
+	if !hasNext || !next.Equal(start.Add(2*time.Second)) {
+		t.Errorf("wrong next expiration, got: %v (%v), want: %v", next, hasNext, start.Add(2*time.Second))
+	}
+}
+
+func TestFakeClockRealTimeInAdvance(t *testing.T) {
+	fc := NewFakeClock()
+	if d := fc.RealTimeInAdvance(); d != 0 {
+		t.Fatalf("got %v before any advance, want 0", d)
+	}
+	for i := 0; i < 100; i++ {
+		fc.NewTimer(time.Duration(i))
+	}
+	fc.Advance(time.Hour)
+	if d := fc.RealTimeInAdvance(); d <= 0 || d > time.Hour {
+		t.Fatalf("got unexpected real time in advance: %v", d)
+	}
+}
+
+func TestFakeClockWouldFire(t *testing.T) {
+	fc := &fakeClock{}
+	if fc.WouldFire(time.Hour) {
+		t.Errorf("idle clock would fire")
+	}
+	fc.NewTimer(5)
+	if fc.WouldFire(4) {
+		t.Errorf("advance short of the timer would fire")
+	}
+	if !fc.WouldFire(5) {
+		t.Errorf("advance up to the timer wouldn't fire")
+	}
+	fc.Advance(4)
+	if !fc.WouldFire(1) {
+		t.Errorf("advance up to the timer wouldn't fire")
+	}
+}
+
+func TestFakeClockStopWhere(t *testing.T) {
+	fc := &fakeClock{}
+	short := fc.NewTimer(1)
+	long := fc.NewTimer(10)
+	var fired bool
+	fc.AfterFunc(10, func() { fired = true })
+	ft := fc.NewTicker(5)
+	fc.BlockUntil(4)
+
+	n := fc.StopWhere(func(w WaiterInfo) bool {
+		return w.Kind == KindTimer && w.FireAt.Sub(fc.Now()) > 5
+	})
+	if n != 1 {
+		t.Errorf("got %d stopped sleepers, want %d", n, 1)
+	}
+	fc.BlockUntil(3)
+	if long.Stop() {
+		t.Errorf("timer stopped by StopWhere was still active")
+	}
+	n = fc.StopWhere(func(w WaiterInfo) bool { return w.Kind == KindAfterFunc })
+	if n != 1 {
+		t.Errorf("got %d stopped sleepers, want %d", n, 1)
+	}
+	fc.BlockUntil(2)
+	fc.Advance(10)
+	select {
+	case <-short.C():
+	default:
+		t.Errorf("timer not matching the predicate didn't fire")
+	}
+	if fired {
+		t.Errorf("func stopped by StopWhere was called")
+	}
+	ft.Stop()
+}
+
+func TestFakeClockPublishExpvar(t *testing.T) {
+	fc := NewFakeClockAt(time.Date(1999, time.February, 3, 4, 5, 6, 0, time.UTC))
+	fc.NewTimer(time.Second)
+	// expvar names cannot be published twice, e.g. with -count.
+	name := "TestFakeClockPublishExpvar"
+	for i := 1; expvar.Get(name) != nil; i++ {
+		name = fmt.Sprintf("TestFakeClockPublishExpvar%d", i)
+	}
+	fc.PublishExpvar(name)
+	got := expvar.Get(name).String()
+	want := `{"now":"1999-02-03T04:05:06Z","waiters":1}`
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestFakeClockFireOnly(t *testing.T) {
+	fc := &fakeClock{}
+	start := fc.Now()
+	one := fc.NewTimer(1)
+	five := fc.NewTimer(5)
+	ten := fc.NewTimer(10)
+	if err := fc.FireOnly(five); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if now := fc.Now(); !now.Equal(start.Add(5)) {
+		t.Errorf("clock at the wrong time, got: %v, want: %v", now, start.Add(5))
+	}
+	select {
+	case <-five.C():
+	default:
+		t.Errorf("fired timer didn't emit time")
+	}
+	select {
+	case <-one.C():
+		t.Errorf("overdue timer was fired")
+	case <-ten.C():
+		t.Errorf("future timer was fired")
+	default:
+	}
+	fc.BlockUntil(2)
+	if err := fc.FireOnly(five); err == nil {
+		t.Errorf("firing an already fired timer didn't fail")
+	}
+	if err := fc.FireOnly(NewFakeClock().NewTimer(1)); err == nil {
+		t.Errorf("firing another clock's timer didn't fail")
+	}
+	fc.Advance(0)
+	select {
+	case <-one.C():
+	default:
+		t.Errorf("overdue timer didn't fire on the next advance")
+	}
+}
+
+func TestFakeClockHerdBatchSize(t *testing.T) {
+	fc := &fakeClock{}
+	fc.SetHerdBatchSize(3)
+	start := fc.Now()
+	var timers []Timer
+	for i := 0; i < 10; i++ {
+		timers = append(timers, fc.NewTimer(5))
+	}
+	later := fc.NewTimer(6)
+	fc.Advance(5)
+	for i, timer := range timers {
+		select {
+		case now := <-timer.C():
+			if !now.Equal(start.Add(5)) {
+				t.Errorf("timer %d fired with the wrong time, got: %v, want: %v", i, now, start.Add(5))
+			}
+		default:
+			t.Errorf("timer %d didn't fire", i)
+		}
+	}
+	select {
+	case <-later.C():
+		t.Errorf("later timer fired prematurely")
+	default:
+	}
+	fc.BlockUntil(1)
+}
+
+// writerFunc is an io.Writer calling a function.
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
+
+func TestFakeClockHerdBatchSizeReleasesLock(t *testing.T) {
+	fc := NewFakeClock()
+	fc.SetHerdBatchSize(1)
+	const n = 20
+	var timers []Timer
+	for i := 0; i < n; i++ {
+		timers = append(timers, fc.NewTimer(time.Second))
+	}
+	last := timers[n-1]
+
+	var fires int32
+	type result struct {
+		fires   int32
+		now     time.Time
+		stopped bool
+	}
+	between := make(chan result, 1)
+	// The fire log is written with the lock held, at each fire.
+	fc.RecordFires(writerFunc(func(p []byte) (int, error) {
+		if atomic.AddInt32(&fires, 1) == 1 {
+			go func() {
+				now := fc.Now()
+				seen := atomic.LoadInt32(&fires)
+				between <- result{fires: seen, now: now, stopped: last.Stop()}
+			}()
+		}
+		// Give the goroutine time to wait for the lock.
+		time.Sleep(time.Millisecond)
+		return len(p), nil
+	}))
+	start := fc.Now()
+	fc.Advance(time.Second)
+	r := <-between
+	if r.fires >= n {
+		t.Errorf("Now returned after all %d fires, the lock was not released between batches", n)
+	}
+	if want := start.Add(time.Second); !r.now.Equal(want) {
+		t.Errorf("got %v between batches, want %v", r.now, want)
+	}
+	select {
+	case <-last.C():
+		if r.stopped {
+			t.Error("timer stopped between batches fired")
+		}
+	default:
+		if !r.stopped {
+			t.Error("last timer neither fired nor was stopped")
+		}
+	}
+}
+
+func TestFakeClockDone(t *testing.T) {
+	fc := &fakeClock{}
+	start := fc.Now()
+	select {
+	case <-fc.Done(start):
+	default:
+		t.Errorf("channel for the current time wasn't closed")
+	}
+	done := fc.Done(start.Add(5))
+	fc.BlockUntil(1)
+	fc.Advance(4)
+	select {
+	case <-done:
+		t.Errorf("channel closed prematurely")
+	default:
+	}
+	fc.Advance(1)
+	select {
+	case <-done:
+	default:
+		t.Errorf("channel wasn't closed when the clock reached its time")
+	}
+	fc.BlockUntil(0)
+}
+
+func TestFakeClockHasBlockers(t *testing.T) {
+	fc := &fakeClock{}
+	if fc.HasBlockers() {
+		t.Fatalf("new clock has blockers")
+	}
+	blocked := make(chan struct{})
+	go func() {
+		fc.BlockUntil(1)
+		close(blocked)
+	}()
+	withTimeout(t, time.Second, func() {
+		for !fc.HasBlockers() {
+			time.Sleep(time.Millisecond)
+		}
+	})
+	fc.NewTimer(1)
+	<-blocked
+	if fc.HasBlockers() {
+		t.Errorf("released blocker is still reported")
+	}
+}
+
+func TestFakeClockHasBlockersSleepersAndAtMost(t *testing.T) {
+	fc := &fakeClock{}
+	waitForBlocker := func() {
+		withTimeout(t, time.Second, func() {
+			for !fc.HasBlockers() {
+				time.Sleep(time.Millisecond)
+			}
+		})
+	}
+	ctx := context.Background()
+
+	sleeping := make(chan error)
+	go func() { sleeping <- fc.BlockUntilSleepers(ctx, 1) }()
+	waitForBlocker()
+	go fc.Sleep(1)
+	<-sleeping
+	if fc.HasBlockers() {
+		t.Errorf("released BlockUntilSleepers caller is still reported")
+	}
+	fc.Advance(1)
+
+	timer := fc.NewTimer(1)
+	drained := make(chan error)
+	go func() { drained <- fc.BlockUntilAtMost(ctx, 0) }()
+	waitForBlocker()
+	timer.Stop()
+	<-drained
+	if fc.HasBlockers() {
+		t.Errorf("released BlockUntilAtMost caller is still reported")
+	}
+}
+
+func TestFakeClockAdvanceToMedian(t *testing.T) {
+	fc := &fakeClock{}
+	start := fc.Now()
+	if n := fc.AdvanceToMedian(); n != 0 || !fc.Now().Equal(start) {
+		t.Fatalf("idle clock: got %d fired at %v, want 0 at %v", n, fc.Now(), start)
+	}
+	for _, d := range []time.Duration{7, 1, 3, 3, 9} {
+		fc.NewTimer(d)
+	}
+	// sorted: 1 3 3 7 9, the median is 3 and both timers at 3 fire.
+	if n := fc.AdvanceToMedian(); n != 3 {
+		t.Errorf("got %d fired, want %d", n, 3)
+	}
+	if now := fc.Now(); !now.Equal(start.Add(3)) {
+		t.Errorf("clock at the wrong time, got: %v, want: %v", now, start.Add(3))
+	}
+	// sorted: 7 9, the median is 7.
+	if n := fc.AdvanceToMedian(); n != 1 {
+		t.Errorf("got %d fired, want %d", n, 1)
+	}
+	if n := fc.AdvanceToMedian(); n != 1 || !fc.Now().Equal(start.Add(9)) {
+		t.Errorf("single sleeper: got %d fired at %v, want 1 at %v", n, fc.Now(), start.Add(9))
+	}
+}
+
+func TestFakeClockAdvanceChecked(t *testing.T) {
+	fc := &fakeClock{}
+	fc.NewTimer(1)
+	fc.NewTimer(2)
+	if err := fc.AdvanceChecked(1); err != nil {
+		t.Errorf("unexpected error without blockers: %v", err)
+	}
+
+	blocked := make(chan struct{})
+	go func() {
+		fc.BlockUntil(2)
+		close(blocked)
+	}()
+	withTimeout(t, time.Second, func() {
+		for !fc.HasBlockers() {
+			time.Sleep(time.Millisecond)
+		}
+	})
+	if err := fc.AdvanceChecked(0); err != nil {
+		t.Errorf("unexpected error with a pending sleeper: %v", err)
+	}
+	if err := fc.AdvanceChecked(1); err == nil {
+		t.Errorf("draining advance with a waiting blocker didn't fail")
+	}
+	fc.NewTimer(1)
+	fc.NewTimer(1)
+	<-blocked
+}
+
+func TestFakeClockAdvanceCheckedSleepers(t *testing.T) {
+	fc := &fakeClock{}
+	fc.NewTimer(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	blocked := make(chan error)
+	go func() { blocked <- fc.BlockUntilSleepers(ctx, 1) }()
+	withTimeout(t, time.Second, func() {
+		for !fc.HasBlockers() {
+			time.Sleep(time.Millisecond)
+		}
+	})
+	err := fc.AdvanceChecked(1)
+	if err == nil || !strings.Contains(err.Error(), "BlockUntilSleepers(1)") {
+		t.Errorf("got %v, want an error about BlockUntilSleepers(1)", err)
+	}
+	cancel()
+	<-blocked
+}
+
+func TestFakeClockDimensions(t *testing.T) {
+	fc := &fakeClock{}
+	start := fc.Now()
+	sim := fc.NewTimer(5)
+	cpu := fc.NewTimerInDimension("cpu", 5)
+	if fc.Dimension("cpu") != fc.Dimension("cpu") {
+		t.Fatalf("Dimension returned different clocks for the same name")
+	}
+
+	fc.Advance(5)
+	select {
+	case <-sim.C():
+	default:
+		t.Errorf("timer of the main axis didn't fire")
+	}
+	select {
+	case <-cpu.C():
+		t.Errorf("timer of the cpu axis fired when the main axis advanced")
+	default:
+	}
+
+	fc.AdvanceDimension("io", 10)
+	fc.AdvanceDimension("cpu", 4)
+	select {
+	case <-cpu.C():
+		t.Errorf("timer of the cpu axis fired prematurely")
+	default:
+	}
+	fc.AdvanceDimension("cpu", 1)
+	select {
+	case <-cpu.C():
+	default:
+		t.Errorf("timer of the cpu axis didn't fire")
+	}
+	if got, want := fc.Dimension("cpu").Now(), start.Add(5); !got.Equal(want) {
+		t.Errorf("cpu axis at the wrong time, got: %v, want: %v", got, want)
+	}
+	// The io axis started when the main axis was already at 5.
+	if got, want := fc.Dimension("io").Now(), start.Add(15); !got.Equal(want) {
+		t.Errorf("io axis at the wrong time, got: %v, want: %v", got, want)
+	}
+	if got, want := fc.Now(), start.Add(5); !got.Equal(want) {
+		t.Errorf("main axis at the wrong time, got: %v, want: %v", got, want)
+	}
+}
+
+func TestTruncateToWindow(t *testing.T) {
+	start := time.Date(2020, time.January, 16, 23, 12, 12, 0, time.UTC)
+	fc := NewFakeClockAt(start)
+	for _, tc := range []struct {
+		t, want time.Time
+	}{
+		{start, start},
+		{start.Add(4 * time.Minute), start},
+		{start.Add(5 * time.Minute), start.Add(5 * time.Minute)},
+		{start.Add(12 * time.Minute), start.Add(10 * time.Minute)},
+		{start.Add(-time.Minute), start.Add(-5 * time.Minute)},
+	} {
+		if got := fc.TruncateToWindow(tc.t, 5*time.Minute); !got.Equal(tc.want) {
+			t.Errorf("TruncateToWindow(%v) = %v, want %v", tc.t, got, tc.want)
+		}
+	}
+
+	rc := NewRealClock()
+	now := rc.Now()
+	if got := rc.TruncateToWindow(now, time.Hour); now.Sub(got) < 0 || now.Sub(got) >= time.Hour {
+		t.Errorf("TruncateToWindow(%v) = %v, not within the window", now, got)
+	}
+}
+
+func TestFakeClockMonotonicSince(t *testing.T) {
+	fc := NewFakeClock()
+	start := fc.Now()
+	fc.Advance(time.Second)
+	if d := fc.MonotonicSince(start); d != time.Second {
+		t.Errorf("got %v, want %v", d, time.Second)
+	}
+	// Stepping the wall time backward doesn't affect the monotonic timeline.
+	fc.Advance(-time.Hour)
+	fc.Advance(time.Second)
+	if d := fc.Since(start); d != -time.Hour+2*time.Second {
+		t.Errorf("got wall time %v, want %v", d, -time.Hour+2*time.Second)
+	}
+	if d := fc.MonotonicSince(start); d != 2*time.Second {
+		t.Errorf("got monotonic time %v, want %v", d, 2*time.Second)
+	}
+}
+
+func TestFakeClockDeliverySink(t *testing.T) {
+	fc := &fakeClock{}
+	start := fc.Now()
+	var seen []WaiterInfo
+	fc.SetDeliverySink(func(e WaiterInfo, at time.Time) bool {
+		if !at.Equal(start.Add(5)) {
+			t.Errorf("sink called with the wrong time, got: %v, want: %v", at, start.Add(5))
+		}
+		// The sink may use the clock.
+		_ = fc.Now()
+		seen = append(seen, e)
+		return e.Kind != KindAfterFunc
+	})
+	timer := fc.NewTimer(5)
+	called := make(chan struct{})
+	fc.AfterFunc(5, func() { close(called) })
+	fc.Advance(5)
+	if len(seen) != 2 || seen[0].Kind != KindTimer || seen[1].Kind != KindAfterFunc {
+		t.Fatalf("sink saw unexpected sleepers: %v", seen)
+	}
+	select {
+	case <-timer.C():
+	default:
+		t.Errorf("timer delivery was suppressed")
+	}
+	select {
+	case <-called:
+		t.Errorf("suppressed func was called")
+	case <-time.After(10 * time.Millisecond):
+	}
+	fc.BlockUntil(0)
+
+	fc.SetDeliverySink(nil)
+	fc.AfterFunc(1, func() { close(called) })
+	fc.Advance(1)
+	withTimeout(t, time.Second, func() { <-called })
+}
+
+func TestFakeClockDeliverySinkReset(t *testing.T) {
+	fc := NewFakeClock()
+	start := fc.Now()
+	timer := fc.NewTimer(time.Second)
+	fc.SetDeliverySink(func(WaiterInfo, time.Time) bool {
+		timer.Reset(time.Hour)
+		return true
+	})
+	fc.Advance(time.Second)
+	select {
+	case got := <-timer.C():
+		t.Fatalf("timer reset by the sink fired at %v", got)
+	default:
+	}
+	if n := fc.WaiterCount(); n != 1 {
+		t.Fatalf("got %d sleepers, want the reset timer", n)
+	}
+
+	fc.SetDeliverySink(nil)
+	fc.Advance(time.Hour)
+	select {
+	case got := <-timer.C():
+		if want := start.Add(time.Second + time.Hour); !got.Equal(want) {
+			t.Errorf("timer fired at %v, want %v", got, want)
+		}
+	default:
+		t.Error("reset timer did not fire at its new expiration")
+	}
+	if n := fc.WaiterCount(); n != 0 {
+		t.Errorf("got %d sleepers after the fire, want 0", n)
+	}
+}
+
+func TestFakeClockDriveUntilQuiescent(t *testing.T) {
+	fc := NewFakeClock()
+	start := fc.Now()
+	done := make(chan struct{})
+	go func() {
+		for _, d := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second} {
+			fc.Sleep(d)
+		}
+		close(done)
+	}()
+	fc.BlockUntil(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := fc.DriveUntilQuiescent(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-done
+	if got, want := fc.Now(), start.Add(6*time.Second); !got.Equal(want) {
+		t.Errorf("clock at the wrong time, got: %v, want: %v", got, want)
+	}
+}
+
+func TestFakeClockDriveUntilQuiescentTicker(t *testing.T) {
+	fc := NewFakeClock()
+	ft := fc.NewTicker(time.Second)
+	defer ft.Stop()
+	fc.BlockUntil(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := fc.DriveUntilQuiescent(ctx); err != context.DeadlineExceeded {
+		t.Errorf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+	if fc.Since(NewFakeClock().Now()) <= 0 {
+		t.Errorf("clock didn't advance")
+	}
+}
+
+func TestFakeClockPerGoroutineReads(t *testing.T) {
+	fc := NewFakeClock()
+	fc.Now()
+	if reads := fc.PerGoroutineReads(); len(reads) != 0 {
+		t.Fatalf("reads counted while tracking was off: %v", reads)
+	}
+	fc.TrackGoroutineReads(true)
+	fc.Now()
+	fc.Now()
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 5; i++ {
+			fc.Now()
+		}
+		close(done)
+	}()
+	<-done
+	fc.TrackGoroutineReads(false)
+	fc.Now()
+
+	reads := fc.PerGoroutineReads()
+	if len(reads) != 2 {
+		t.Fatalf("got reads for %d goroutines, want %d: %v", len(reads), 2, reads)
+	}
+	if n := reads[goroutineID()]; n != 2 {
+		t.Errorf("got %d reads for the test goroutine, want %d", n, 2)
+	}
+	total := 0
+	for _, n := range reads {
+		total += n
+	}
+	if total != 7 {
+		t.Errorf("got %d reads in total, want %d", total, 7)
+	}
+}
+
+func TestFakeClockSetDilation(t *testing.T) {
+	fc := NewFakeClock()
+	start := fc.Now()
+	target := start.Add(time.Hour)
+	// Time runs at full speed until the last 10 minutes, then at a tenth.
+	fc.SetDilation(target, func(remaining time.Duration) float64 {
+		if remaining > 10*time.Minute {
+			return 1
+		}
+		return 0.1
+	})
+	timer := fc.NewTimer(51 * time.Minute)
+
+	fc.Advance(50 * time.Minute)
+	if got, want := fc.Now(), start.Add(50*time.Minute); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	fc.Advance(5 * time.Minute)
+	if got, want := fc.Now(), start.Add(50*time.Minute+30*time.Second); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	select {
+	case <-timer.C():
+		t.Fatal("timer fired before its expiration was reached")
+	default:
+	}
+	fc.Advance(5 * time.Minute)
+	select {
+	case <-timer.C():
+	default:
+		t.Fatal("timer did not fire once its expiration was reached")
+	}
+
+	fc.SetDilation(time.Time{}, nil)
+	before := fc.Now()
+	fc.Advance(time.Minute)
+	if got := fc.Since(before); got != time.Minute {
+		t.Errorf("got an advance of %v after removing the dilation, want %v", got, time.Minute)
+	}
+}
+
+func TestFakeClockResetAll(t *testing.T) {
+	fc := NewFakeClock()
+	t1 := fc.NewTimer(time.Second)
+	t2 := fc.NewTimer(2 * time.Second)
+	t3 := fc.NewUnarmedTimer()
+	other := NewFakeClock().NewTimer(time.Second)
+
+	err := fc.ResetAll(map[Timer]time.Duration{t1: time.Minute, other: time.Minute})
+	if err == nil {
+		t.Fatal("expected an error resetting a timer of another clock")
+	}
+	fc.Advance(time.Second)
+	select {
+	case <-t1.C():
+	default:
+		t.Fatal("t1 was reset by a rejected ResetAll")
+	}
+
+	err = fc.ResetAll(map[Timer]time.Duration{
+		t1: 3 * time.Second,
+		t2: 3 * time.Second,
+		t3: 3 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("ResetAll returned an error: %v", err)
+	}
+	fc.Advance(2 * time.Second)
+	for i, timer := range []Timer{t1, t2, t3} {
+		select {
+		case <-timer.C():
+			t.Errorf("timer %d fired before its new expiration", i+1)
+		default:
+		}
+	}
+	fc.Advance(time.Second)
+	for i, timer := range []Timer{t1, t2, t3} {
+		select {
+		case <-timer.C():
+		default:
+			t.Errorf("timer %d did not fire at its new expiration", i+1)
+		}
+	}
+	if _, _, hasNext := fc.State(); hasNext {
+		t.Error("sleepers left after all the timers fired")
+	}
+}
+
+func TestFakeClockTimeToDrain(t *testing.T) {
+	fc := NewFakeClock()
+	if d := fc.TimeToDrain(); d != 0 {
+		t.Fatalf("got %v with no sleepers, want 0", d)
+	}
+	ticker := fc.NewTicker(time.Hour)
+	defer ticker.Stop()
+	fc.BlockUntil(1)
+	if d := fc.TimeToDrain(); d != 0 {
+		t.Fatalf("got %v with only a ticker, want 0", d)
+	}
+	fc.NewTimer(time.Second)
+	fc.AfterFunc(3*time.Second, func() {})
+	fc.NewTimer(2 * time.Second)
+	if d, want := fc.TimeToDrain(), 3*time.Second; d != want {
+		t.Fatalf("got %v, want %v", d, want)
+	}
+	fc.Advance(time.Second)
+	if d, want := fc.TimeToDrain(), 2*time.Second; d != want {
+		t.Fatalf("got %v after an advance, want %v", d, want)
+	}
+}
+
+func TestFakeClockIdleChan(t *testing.T) {
+	fc := NewFakeClock()
+	select {
+	case <-fc.IdleChan():
+	default:
+		t.Fatal("IdleChan of an idle clock is not closed")
+	}
+
+	fc.NewTimer(time.Second)
+	timer := fc.NewTimer(2 * time.Second)
+	idle := fc.IdleChan()
+	fc.Advance(time.Second)
+	select {
+	case <-idle:
+		t.Fatal("IdleChan closed with a sleeper left")
+	default:
+	}
+	timer.Stop()
+	select {
+	case <-idle:
+	default:
+		t.Fatal("IdleChan not closed once the last sleeper was stopped")
+	}
+
+	fc.NewTimer(time.Second)
+	idle = fc.IdleChan()
+	fc.Advance(time.Second)
+	select {
+	case <-idle:
+	default:
+		t.Fatal("IdleChan not closed once the last sleeper fired")
+	}
+}
+
+func TestFakeClockIdleChanTickerStop(t *testing.T) {
+	fc := NewFakeClock()
+	ticker := fc.NewTicker(time.Second)
+	fc.BlockUntil(1)
+	idle := fc.IdleChan()
+	ticker.Stop()
+	if n := fc.WaiterCount(); n != 0 {
+		t.Errorf("got %d waiters after the ticker was stopped, want 0", n)
+	}
+	select {
+	case <-idle:
+	default:
+		t.Fatal("IdleChan not closed once the ticker was stopped")
+	}
+	// Stopping again is a no-op.
+	withTimeout(t, time.Second, ticker.Stop)
+}
+
+func TestFakeClockRegisterMetrics(t *testing.T) {
+	fc := NewFakeClockAt(time.Unix(1000, 0))
+	var times, waiters []float64
+	fc.RegisterMetrics(
+		func(v float64) { times = append(times, v) },
+		func(v float64) { waiters = append(waiters, v) },
+	)
+	timer := fc.NewTimer(time.Second)
+	fc.NewTimer(2 * time.Second)
+	timer.Stop()
+	fc.Advance(1500 * time.Millisecond)
+
+	wantTimes := []float64{1000, 1000, 1000, 1000, 1001.5}
+	wantWaiters := []float64{0, 1, 2, 1, 1}
+	if !reflect.DeepEqual(times, wantTimes) {
+		t.Errorf("got times %v, want %v", times, wantTimes)
+	}
+	if !reflect.DeepEqual(waiters, wantWaiters) {
+		t.Errorf("got waiters %v, want %v", waiters, wantWaiters)
+	}
+
+	fc.RegisterMetrics(nil, nil)
+	fc.Advance(time.Second)
+	if len(times) != len(wantTimes) {
+		t.Errorf("callbacks still called after being removed")
+	}
+}
+
+func TestFakeClockFiresBeforeTimer(t *testing.T) {
+	fc := NewFakeClock()
+	timeout := fc.NewTimer(time.Second)
+	retry := fc.NewTimer(2 * time.Second)
+	same := fc.NewTimer(2 * time.Second)
+
+	if before, err := fc.FiresBeforeTimer(timeout, retry); err != nil || !before {
+		t.Errorf("got %v (%v), want timeout before retry", before, err)
+	}
+	if before, err := fc.FiresBeforeTimer(retry, timeout); err != nil || before {
+		t.Errorf("got %v (%v), want retry not before timeout", before, err)
+	}
+	if before, err := fc.FiresBeforeTimer(retry, same); err != nil || before {
+		t.Errorf("got %v (%v) for timers expiring together, want false", before, err)
+	}
+
+	fc.Advance(time.Second)
+	if _, err := fc.FiresBeforeTimer(timeout, retry); err != errNotWaiting {
+		t.Errorf("got %v for a fired timer, want %v", err, errNotWaiting)
+	}
+	if _, err := fc.FiresBeforeTimer(retry, fc.NewUnarmedTimer()); err != errNotWaiting {
+		t.Errorf("got %v for an unarmed timer, want %v", err, errNotWaiting)
+	}
+}
+
+func TestFakeClockSetYieldAfterFire(t *testing.T) {
+	fc := NewFakeClock()
+	fc.SetYieldAfterFire(true)
+	const rounds = 100
+	observed := 0
+	for i := 0; i < rounds; i++ {
+		var ran int32
+		timer := fc.NewTimer(time.Second)
+		done := make(chan struct{})
+		go func() {
+			<-timer.C()
+			atomic.StoreInt32(&ran, 1)
+			close(done)
+		}()
+		fc.Advance(time.Second)
+		if atomic.LoadInt32(&ran) == 1 {
+			observed++
+		}
+		<-done
+	}
+	// Yielding is best effort, the woken goroutine is not guaranteed to run
+	// before Advance returns, but it should most of the time.
+	if observed == 0 {
+		t.Errorf("the woken goroutine never ran before Advance returned in %d rounds", rounds)
+	}
+}
+
+func TestFakeClockSetYieldAfterFireBetweenFires(t *testing.T) {
+	// With a single P, the woken goroutine can only run between the fires
+	// when the advancing one yields. The yield is best effort, hence the
+	// rounds.
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+	const rounds = 20
+	ran := map[bool]int{}
+	for _, yield := range []bool{false, true} {
+		for i := 0; i < rounds; i++ {
+			fc := NewFakeClock()
+			fc.SetYieldAfterFire(yield)
+			second := fc.NewTimer(2 * time.Second)
+			betweenFires := make(chan bool, 1)
+			go func() {
+				fc.Sleep(time.Second)
+				// Reset reports whether second was still pending.
+				betweenFires <- second.Reset(time.Hour)
+			}()
+			fc.BlockUntil(2)
+			fc.Advance(2 * time.Second)
+			reset := <-betweenFires
+			select {
+			case <-second.C():
+				if reset {
+					t.Errorf("yield %v: timer reset between the fires fired", yield)
+				}
+			default:
+				if !reset {
+					t.Errorf("yield %v: second timer did not fire", yield)
+				}
+			}
+			if reset {
+				ran[yield]++
+			}
+		}
+	}
+	if ran[true] <= ran[false] {
+		t.Errorf("the woken goroutine ran between the fires in %d rounds with yield, %d without", ran[true], ran[false])
+	}
+}
+
+func TestClockParse(t *testing.T) {
+	loc := time.FixedZone("local", -3600)
+	const layout, value = "2006-01-02 15:04", "2020-01-16 23:12"
+	for _, tc := range []struct {
+		name  string
+		clock Clock
+		loc   *time.Location
+	}{
+		{"real", NewRealClock(), time.UTC},
+		{"real in location", NewRealClockInLocation(loc), loc},
+		{"fake", NewFakeClock(), time.UTC},
+		{"fake in location", NewFakeClock(WithLocation(loc)), loc},
+	} {
+		got, err := tc.clock.Parse(layout, value)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if want := time.Date(2020, 1, 16, 23, 12, 0, 0, tc.loc); !got.Equal(want) || got.Location() != tc.loc {
+			t.Errorf("%s: got %v, want %v", tc.name, got, want)
+		}
+	}
+	if _, err := NewFakeClock().Parse(layout, "not a time"); err == nil {
+		t.Error("expected an error parsing an invalid value")
+	}
+}
+
+func TestFakeClockSetMaxWaiters(t *testing.T) {
+	fc := NewFakeClock()
+	fc.SetMaxWaiters(2)
+	fc.NewTimer(time.Second)
+	fc.NewTimer(time.Second)
+	// Timers firing right away are never pending.
+	fc.NewTimer(0)
+
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("expected a panic scheduling a third timer")
+			}
+		}()
+		fc.NewTimer(time.Second)
+	}()
+
+	// The clock is still usable after the panic.
+	fc.Advance(time.Second)
+	fc.NewTimer(time.Second)
+	fc.SetMaxWaiters(0)
+	for i := 0; i < 10; i++ {
+		fc.NewTimer(time.Second)
+	}
+}
+
+func TestFakeClockPeekFire(t *testing.T) {
+	fc := NewFakeClock()
+	timer := fc.NewTimer(time.Second)
+	if _, ok := fc.PeekFire(timer); ok {
+		t.Fatal("peeked at a timer which did not fire")
+	}
+	fc.Advance(time.Second)
+	want := fc.Now()
+	for i := 0; i < 2; i++ {
+		if got, ok := fc.PeekFire(timer); !ok || !got.Equal(want) {
+			t.Fatalf("got %v (%v), want %v", got, ok, want)
+		}
+	}
+	select {
+	case got := <-timer.C():
+		if !got.Equal(want) {
+			t.Errorf("received %v after peeking, want %v", got, want)
+		}
+	default:
+		t.Fatal("the peeked value was consumed")
+	}
+	if _, ok := fc.PeekFire(timer); ok {
+		t.Error("peeked at a value already received")
+	}
+	if _, ok := fc.PeekFire(fc.AfterFunc(0, func() {})); ok {
+		t.Error("peeked at an AfterFunc timer")
+	}
+}
+
+func TestFakeClockBlockUntilAtMost(t *testing.T) {
+	fc := NewFakeClock()
+	if err := fc.BlockUntilAtMost(context.Background(), 0); err != nil {
+		t.Fatalf("got %v with no sleeper, want nil", err)
+	}
+	fc.NewTimer(time.Second)
+	stopped := fc.NewTimer(time.Minute)
+	fc.NewTimer(time.Hour)
+
+	blocked := make(chan error)
+	go func() {
+		blocked <- fc.BlockUntilAtMost(context.Background(), 1)
+	}()
+	fc.Advance(time.Second)
+	select {
+	case err := <-blocked:
+		t.Fatalf("returned %v with 2 sleepers", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	stopped.Stop()
+	select {
+	case err := <-blocked:
+		if err != nil {
+			t.Fatalf("got %v, want nil", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the sleepers to drain")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := fc.BlockUntilAtMost(ctx, 0); err != context.DeadlineExceeded {
+		t.Errorf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestFakeClockBlockersNotifiedOnRemoval(t *testing.T) {
+	fc := NewFakeClock()
+	fc.NewTimer(time.Second)
+	stopped := fc.NewTimer(time.Minute)
+	fc.NewTimer(time.Hour)
+	fc.BlockUntil(3)
+
+	// a count only reached as the sleepers drain
+	blocked := make(chan struct{})
+	go func() {
+		fc.BlockUntil(1)
+		close(blocked)
+	}()
+	fc.Advance(time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := fc.BlockUntilAtMost(ctx, 2); err != nil {
+		t.Fatalf("got %v waiting for 2 sleepers after a fire", err)
+	}
+	stopped.Stop()
+	select {
+	case <-blocked:
+	case <-time.After(time.Second):
+		t.Fatal("BlockUntil was not released when a sleeper was stopped")
+	}
+}
+
+func TestFakeClockBlockUntilSleepers(t *testing.T) {
+	fc := NewFakeClock()
+	fc.NewTimer(time.Second)
+	ticker := fc.NewTicker(time.Second)
+	defer ticker.Stop()
+	if err := fc.BlockUntilSleepers(context.Background(), 0); err != nil {
+		t.Fatalf("got %v with no caller of Sleep, want nil", err)
+	}
+
+	blocked := make(chan error)
+	go func() {
+		blocked <- fc.BlockUntilSleepers(context.Background(), 2)
+	}()
+	slept := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		go func() {
+			fc.Sleep(time.Minute)
+			slept <- struct{}{}
+		}()
+	}
+	select {
+	case err := <-blocked:
+		if err != nil {
+			t.Fatalf("got %v, want nil", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the sleepers")
+	}
+	fc.Advance(time.Minute)
+	for i := 0; i < 2; i++ {
+		<-slept
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := fc.BlockUntilSleepers(ctx, 1); err != context.DeadlineExceeded {
+		t.Errorf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+	if n := len(fc.(*fakeClock).sleepBlockers); n != 0 {
+		t.Error("the blocker was not removed when its context expired")
+	}
+}
+
+func TestFakeClockAdvanceRandom(t *testing.T) {
+	advances := func(seed int64) []time.Duration {
+		fc := NewFakeClock()
+		start := fc.Now()
+		var total time.Duration
+		ds := make([]time.Duration, 20)
+		for i := range ds {
+			ds[i] = fc.AdvanceRandom(time.Second, 2*time.Second, seed)
+			if ds[i] < time.Second || ds[i] > 2*time.Second {
+				t.Fatalf("advanced by %v, out of [1s, 2s]", ds[i])
+			}
+			total += ds[i]
+		}
+		if got := fc.Since(start); got != total {
+			t.Fatalf("clock advanced by %v, want %v", got, total)
+		}
+		return ds
+	}
+	first, second := advances(42), advances(42)
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("same seed gave different advances: %v and %v", first, second)
+	}
+	if reflect.DeepEqual(first, advances(43)) {
+		t.Errorf("different seeds gave the same advances: %v", first)
+	}
+
+	fc := NewFakeClock()
+	if d := fc.AdvanceRandom(time.Second, time.Second, 1); d != time.Second {
+		t.Errorf("got %v with min == max, want %v", d, time.Second)
+	}
+}
+
+func TestFakeClockSetFireBias(t *testing.T) {
+	fc := NewFakeClock()
+	start := fc.Now()
+	fc.SetFireBias(100 * time.Millisecond)
+	late := fc.NewTimer(time.Second)
+	fc.Advance(time.Second)
+	if fc.WouldFire(99 * time.Millisecond) {
+		t.Error("WouldFire reports a fire before the biased expiration")
+	}
+	select {
+	case <-late.C():
+		t.Fatal("timer fired at its nominal expiration despite a late bias")
+	default:
+	}
+	fc.Advance(100 * time.Millisecond)
+	select {
+	case got := <-late.C():
+		if want := start.Add(1100 * time.Millisecond); !got.Equal(want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	default:
+		t.Fatal("timer did not fire at its biased expiration")
+	}
+
+	fc.SetFireBias(-100 * time.Millisecond)
+	early := fc.NewTimer(time.Second)
+	fc.Advance(900 * time.Millisecond)
+	select {
+	case <-early.C():
+	default:
+		t.Fatal("timer did not fire early despite an early bias")
+	}
+}
+
+func TestFakeClockSetFireBiasHelpers(t *testing.T) {
+	fc := NewFakeClock()
+	start := fc.Now()
+	fc.SetFireBias(100 * time.Millisecond)
+	fc.NewTimer(time.Second)
+	fc.NewTimer(2 * time.Second)
+	fc.NewTimer(3 * time.Second)
+	if got, want := fc.TimeToDrain(), 3100*time.Millisecond; got != want {
+		t.Errorf("got TimeToDrain %v, want %v", got, want)
+	}
+	// The median is the timer at 2s, which fires at 2.1s along with the
+	// one at 1s.
+	if n := fc.AdvanceToMedian(); n != 2 {
+		t.Errorf("AdvanceToMedian fired %d sleepers, want 2", n)
+	}
+	if want := start.Add(2100 * time.Millisecond); !fc.Now().Equal(want) {
+		t.Errorf("got %v after AdvanceToMedian, want %v", fc.Now(), want)
+	}
+}
+
+func TestFakeClockAdvanceTo(t *testing.T) {
+	fc := NewFakeClock()
+	target := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	before := fc.AfterFunc(target.Sub(fc.Now())-time.Second, func() {})
+	after := fc.NewTimer(target.Sub(fc.Now()) + time.Second)
+	exact := fc.NewTimer(target.Sub(fc.Now()))
+
+	fc.AdvanceTo(target)
+	if now := fc.Now(); !now.Equal(target) {
+		t.Fatalf("got %v, want %v", now, target)
+	}
+	if before.Stop() {
+		t.Error("the timer expiring before the target did not fire")
+	}
+	select {
+	case got := <-exact.C():
+		if !got.Equal(target) {
+			t.Errorf("got %v, want %v", got, target)
+		}
+	default:
+		t.Error("the timer expiring at the target did not fire")
+	}
+	select {
+	case <-after.C():
+		t.Error("the timer expiring after the target fired")
+	default:
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expected a panic advancing to the past")
+		}
+	}()
+	fc.AdvanceTo(target.Add(-time.Nanosecond))
+}
+
+func TestFakeClockOnAdvance(t *testing.T) {
+	fc := NewFakeClock()
+	start := fc.Now()
+	timer := fc.NewTimer(time.Second)
+	var calls []string
+	fc.OnAdvance(func(from, to time.Time) {
+		select {
+		case <-timer.C():
+			calls = append(calls, "fired")
+		default:
+		}
+		calls = append(calls, fmt.Sprintf("first %v %v", from.Sub(start), to.Sub(start)))
+	})
+	fc.OnAdvance(func(from, to time.Time) {
+		// the lock is released, the clock can be used
+		calls = append(calls, fmt.Sprintf("second %v", fc.Since(start)))
+	})
+	fc.Advance(time.Second)
+	fc.SetTime(start)
+	fc.AdvanceTo(start.Add(time.Minute))
+	want := []string{"fired", "first 0s 1s", "second 1s", "first 0s 1m0s", "second 1m0s"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("got calls %q, want %q", calls, want)
+	}
+}
+
+func TestFakeClockOnAdvanceConcurrentUse(t *testing.T) {
+	fc := NewFakeClock()
+	start := fc.Now()
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				fc.NewTimer(time.Hour).Stop()
+			}
+		}
+	}()
+	defer func() {
+		close(stop)
+		<-done
+	}()
+
+	advancer := goroutineID()
+	var calls []time.Duration
+	fc.OnAdvance(func(from, to time.Time) {
+		if id := goroutineID(); id != advancer {
+			t.Errorf("hook called on goroutine %d, not on the advancing goroutine %d", id, advancer)
+		}
+		calls = append(calls, to.Sub(start))
+	})
+	iterations := 10000
+	if testing.Short() {
+		iterations = 1000
+	}
+	for i := 1; i <= iterations; i++ {
+		fc.Advance(time.Second)
+		if len(calls) != i || calls[i-1] != time.Duration(i)*time.Second {
+			t.Fatalf("advance %d: got %d hook calls, want %d ending with %v", i, len(calls), i, time.Duration(i)*time.Second)
+		}
+	}
+}
+
+func TestFakeClockRewind(t *testing.T) {
+	fc := NewFakeClock()
+	start := fc.Now()
+	timer := fc.NewTimer(time.Second)
+	fc.Rewind(500 * time.Millisecond)
+	if got := fc.Since(start); got != -500*time.Millisecond {
+		t.Errorf("got Since %v after rewinding, want %v", got, -500*time.Millisecond)
+	}
+	if got := fc.MonotonicSince(start); got != 0 {
+		t.Errorf("got MonotonicSince %v after rewinding, want 0", got)
+	}
+	fc.Advance(time.Second)
+	select {
+	case <-timer.C():
+		t.Fatal("timer fired before its expiration")
+	default:
+	}
+	fc.Advance(500 * time.Millisecond)
+	select {
+	case got := <-timer.C():
+		if want := start.Add(time.Second); !got.Equal(want) {
+			t.Errorf("timer fired at %v, want %v", got, want)
+		}
+	default:
+		t.Fatal("timer did not fire at its expiration")
+	}
+	assert.Panics(t, func() { fc.Rewind(-time.Second) })
+}
+
+func TestFakeClockAdvanceAndWait(t *testing.T) {
+	fc := NewFakeClock()
+	var fired, nested int32
+	fc.AfterFunc(time.Second, func() {
+		time.Sleep(10 * time.Millisecond)
+		atomic.StoreInt32(&fired, 1)
+		fc.AfterFunc(0, func() {
+			time.Sleep(10 * time.Millisecond)
+			atomic.StoreInt32(&nested, 1)
+		})
+	})
+	fc.AdvanceAndWait(time.Second)
+	if atomic.LoadInt32(&fired) != 1 {
+		t.Error("AfterFunc had not returned after AdvanceAndWait")
+	}
+	if atomic.LoadInt32(&nested) != 1 {
+		t.Error("nested AfterFunc had not returned after AdvanceAndWait")
+	}
+	// nothing to wait for
+	fc.AdvanceAndWait(time.Second)
+}
+
+func TestFakeClockAdvanceAndWaitConcurrentUse(t *testing.T) {
+	fc := NewFakeClock()
+	iterations := 500
+	if testing.Short() {
+		iterations = 50
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				fc.AfterFunc(0, func() {})
+				fc.AfterFunc(time.Millisecond, func() {})
+				fc.AdvanceAndWait(time.Millisecond)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestFakeClockReset(t *testing.T) {
+	fc := &fakeClock{}
+	timer := fc.NewTimer(time.Second)
+	ticker := fc.NewTicker(time.Second)
+	defer ticker.Stop()
+	fc.BlockUntil(2)
+	blocked := make(chan struct{})
+	go func() {
+		fc.BlockUntil(5)
+		close(blocked)
+	}()
+	for registered := false; !registered; {
+		time.Sleep(time.Millisecond)
+		fc.l.RLock()
+		registered = len(fc.blockers) == 1
+		fc.l.RUnlock()
+	}
+
+	start := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	fc.Reset(start)
+	select {
+	case <-blocked:
+	case <-time.After(time.Second):
+		t.Fatal("BlockUntil was not released by Reset")
+	}
+	if now := fc.Now(); !now.Equal(start) {
+		t.Errorf("got %v after Reset, want %v", now, start)
+	}
+	if n := fc.WaiterCount(); n != 0 {
+		t.Errorf("got %d sleepers after Reset, want 0", n)
+	}
+	fc.Advance(time.Hour)
+	select {
+	case <-timer.C():
+		t.Error("timer fired after Reset")
+	case <-ticker.Chan():
+		t.Error("ticker ticked after Reset")
+	default:
+	}
+	if timer.Stop() {
+		t.Error("timer was still active after Reset")
+	}
+	timer.Reset(time.Second)
+	fc.Advance(time.Second)
+	select {
+	case <-timer.C():
+	default:
+		t.Error("timer did not fire once armed again")
+	}
+}
+
+func TestFakeClockSetTime(t *testing.T) {
+	fc := NewFakeClock()
+	start := fc.Now()
+	timer := fc.NewTimer(time.Minute)
+	other := fc.NewTimer(time.Hour)
+
+	stepped := start.Add(2 * time.Minute)
+	fc.SetTime(stepped)
+	if now := fc.Now(); !now.Equal(stepped) {
+		t.Fatalf("got %v, want %v", now, stepped)
+	}
+	if d := fc.Since(start); d != 2*time.Minute {
+		t.Errorf("got Since %v, want %v", d, 2*time.Minute)
+	}
+	if d := fc.MonotonicSince(start); d != 0 {
+		t.Errorf("SetTime moved the monotonic time by %v", d)
+	}
+	// Stopping another timer does not notify the overdue one.
+	other.Stop()
+	select {
+	case <-timer.C():
+		t.Fatal("SetTime notified an overdue timer")
+	default:
+	}
+	fc.Advance(0)
+	select {
+	case got := <-timer.C():
+		if !got.Equal(stepped) {
+			t.Errorf("got %v, want %v", got, stepped)
+		}
+	default:
+		t.Fatal("the next advance did not notify the overdue timer")
+	}
+
+	fc.SetTime(start)
+	fc.Advance(time.Second)
+	if now, want := fc.Now(), start.Add(time.Second); !now.Equal(want) {
+		t.Errorf("got %v after stepping back, want %v", now, want)
+	}
+}
+
+func TestFakeClockSetAutoAdvance(t *testing.T) {
+	fc := NewFakeClock()
+	start := fc.Now()
+	fc.SetAutoAdvance(time.Minute)
+
+	withTimeout(t, time.Second, func() {
+		fc.Sleep(time.Second)
+		fc.Sleep(2 * time.Second)
+	})
+	if now, want := fc.Now(), start.Add(3*time.Second); !now.Equal(want) {
+		t.Fatalf("got %v, want %v", now, want)
+	}
+
+	// A sleeper beyond step is left for an explicit advance.
+	far := fc.NewTimer(time.Hour)
+	if now, want := fc.Now(), start.Add(3*time.Second); !now.Equal(want) {
+		t.Fatalf("auto-advanced to a sleeper beyond step: got %v, want %v", now, want)
+	}
+	// Another sleeper is pending, the clock does not jump to the new one.
+	near := fc.NewTimer(time.Second)
+	select {
+	case <-near.C():
+		t.Fatal("auto-advanced while another sleeper was pending")
+	default:
+	}
+	fc.Advance(time.Hour)
+	<-far.C()
+	<-near.C()
+
+	fc.SetAutoAdvance(0)
+	timer := fc.NewTimer(time.Second)
+	select {
+	case <-timer.C():
+		t.Fatal("auto-advanced after the mode was turned off")
+	default:
+	}
+}
+
+func TestFakeClockPendingAfterFuncs(t *testing.T) {
+	fc := NewFakeClock()
+	fc.AfterFuncNamed(2*time.Second, "retry", func() {})
+	fc.AfterFuncNamed(time.Second, "cleanup", func() {})
+	fc.AfterFunc(3*time.Second, func() {})
+	fc.NewTimer(time.Second)
+	if got, want := fc.PendingAfterFuncs(), []string{"cleanup", "retry", ""}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	fc.Advance(time.Second)
+	if got, want := fc.PendingAfterFuncs(), []string{"retry", ""}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q after the cleanup fired, want %q", got, want)
+	}
+	fc.Advance(2 * time.Second)
+	if got := fc.PendingAfterFuncs(); len(got) != 0 {
+		t.Fatalf("got %q once all fired, want none", got)
+	}
+}
+
+func TestFakeClockSleepContext(t *testing.T) {
+	fc := NewFakeClock()
+	errs := make(chan error)
+	go func() {
+		errs <- fc.SleepContext(context.Background(), time.Second)
+	}()
+	fc.BlockUntil(1)
+	fc.Advance(time.Second)
+	select {
+	case err := <-errs:
+		if err != nil {
+			t.Fatalf("got %v, want nil", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the sleep")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		errs <- fc.SleepContext(ctx, time.Second)
+	}()
+	fc.BlockUntil(1)
+	cancel()
+	select {
+	case err := <-errs:
+		if err != context.Canceled {
+			t.Fatalf("got %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the canceled sleep")
+	}
+	// The sleeper is gone once the context won.
+	withTimeout(t, time.Second, func() { fc.BlockUntil(0) })
+
+	if err := fc.SleepContext(ctx, 0); err != context.Canceled {
+		t.Errorf("got %v with a done context, want %v", err, context.Canceled)
+	}
+}
+
+func TestRealClockSleepContext(t *testing.T) {
+	rc := NewRealClock()
+	if err := rc.SleepContext(context.Background(), time.Millisecond); err != nil {
+		t.Errorf("got %v, want nil", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := rc.SleepContext(ctx, time.Hour); err != context.Canceled {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestFakeClockNewTimerDedup(t *testing.T) {
+	fc := NewFakeClock().(*fakeClock)
+	first := fc.NewTimerDedup("timeout", time.Second)
+	fc.Advance(500 * time.Millisecond)
+	second := fc.NewTimerDedup("timeout", time.Second)
+	other := fc.NewTimerDedup("other", time.Second)
+	if second != first {
+		t.Fatal("a pending keyed timer was not reused")
+	}
+	if other == first {
+		t.Fatal("a timer was reused across keys")
+	}
+	if n := len(fc.Waiters()); n != 2 {
+		t.Fatalf("got %d sleepers, want %d", n, 2)
+	}
+
+	// The reused timer was reset.
+	fc.Advance(500 * time.Millisecond)
+	select {
+	case <-first.C():
+		t.Fatal("the keyed timer fired at its original expiration")
+	default:
+	}
+	fc.Advance(500 * time.Millisecond)
+	select {
+	case <-first.C():
+	default:
+		t.Fatal("the keyed timer did not fire at its new expiration")
+	}
+
+	// Once fired, the key gets a fresh timer.
+	if fresh := fc.NewTimerDedup("timeout", time.Second); fresh == first {
+		t.Error("a fired keyed timer was reused")
+	}
+
+	// Re-arming a keyed timer does not look like a sleeper going away.
+	stopped := fc.NewTimerDedup("stopped", time.Hour)
+	blocked := make(chan struct{})
+	go func() {
+		fc.BlockUntil(1)
+		close(blocked)
+	}()
+	for registered := false; !registered; {
+		time.Sleep(time.Millisecond)
+		fc.l.RLock()
+		registered = len(fc.blockers) == 1
+		fc.l.RUnlock()
+	}
+	fc.NewTimerDedup("timeout", time.Second)
+	select {
+	case <-blocked:
+		t.Error("re-arming a keyed timer released BlockUntil(1) with 2 sleepers")
+	default:
+	}
+
+	// Stopped and fired keyed timers are forgotten.
+	stopped.Stop()
+	<-blocked
+	fc.Advance(time.Second)
+	fc.l.RLock()
+	keys := len(fc.dedup)
+	fc.l.RUnlock()
+	if keys != 0 {
+		t.Errorf("got %d keyed timers left, want 0", keys)
+	}
+}
+
+func TestFakeClockWaiterCount(t *testing.T) {
+	fc := NewFakeClock()
+	if n := fc.WaiterCount(); n != 0 {
+		t.Fatalf("got %d, want %d", n, 0)
+	}
+	timer := fc.NewTimer(time.Second)
+	fc.AfterFunc(2*time.Second, func() {})
+	if n := fc.WaiterCount(); n != 2 {
+		t.Fatalf("got %d, want %d", n, 2)
+	}
+	timer.Stop()
+	if n := fc.WaiterCount(); n != 1 {
+		t.Fatalf("got %d after a stop, want %d", n, 1)
+	}
+	fc.Advance(2 * time.Second)
+	if n := fc.WaiterCount(); n != 0 {
+		t.Fatalf("got %d after an advance, want %d", n, 0)
+	}
+}
+
+func TestFakeClockAtLeast(t *testing.T) {
+	fc := NewFakeClock()
+	done := make(chan struct{})
+	go func() {
+		fc.AtLeast(time.Second, func() { fc.Advance(300 * time.Millisecond) })
+		close(done)
+	}()
+	// f was faster than d, the rest is padded.
+	fc.BlockUntil(1)
+	fc.Advance(699 * time.Millisecond)
+	select {
+	case <-done:
+		t.Fatal("AtLeast returned before d elapsed")
+	case <-time.After(10 * time.Millisecond):
+	}
+	fc.Advance(time.Millisecond)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for AtLeast")
+	}
+
+	// f was slower than d, there is nothing to pad.
+	withTimeout(t, time.Second, func() {
+		fc.AtLeast(time.Second, func() { fc.Advance(2 * time.Second) })
+	})
+	if n := fc.WaiterCount(); n != 0 {
+		t.Errorf("got %d sleepers after a slow f, want none", n)
+	}
+}
+
+func TestRealClockAtLeast(t *testing.T) {
+	rc := NewRealClock()
+	start := time.Now()
+	rc.AtLeast(20*time.Millisecond, func() {})
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("AtLeast returned after %v, want at least %v", elapsed, 20*time.Millisecond)
+	}
+}
+
+func TestFakeClockAdvanceToNextTimer(t *testing.T) {
+	fc := NewFakeClock()
+	if d := fc.AdvanceToNextTimer(); d != 0 {
+		t.Fatalf("got %v with no sleepers, want 0", d)
+	}
+	first := fc.NewTimer(2 * time.Second)
+	same := fc.NewTimer(2 * time.Second)
+	last := fc.NewTimer(5 * time.Second)
+
+	if d := fc.AdvanceToNextTimer(); d != 2*time.Second {
+		t.Fatalf("got %v, want %v", d, 2*time.Second)
+	}
+	for i, timer := range []Timer{first, same} {
+		select {
+		case <-timer.C():
+		default:
+			t.Errorf("timer %d expiring at the next instant did not fire", i)
+		}
+	}
+	select {
+	case <-last.C():
+		t.Fatal("a later timer fired")
+	default:
+	}
+	if d := fc.AdvanceToNextTimer(); d != 3*time.Second {
+		t.Fatalf("got %v, want %v", d, 3*time.Second)
+	}
+	select {
+	case <-last.C():
+	default:
+		t.Fatal("the last timer did not fire")
+	}
+}
+
+func TestFakeClockPriorityOrder(t *testing.T) {
+	fc := NewFakeClock()
+	fc.AfterFuncNamed(time.Second, "first", func() {})
+	fc.NewTimerWithPriority(time.Second, 1)
+	fc.NewTimerWithPriority(500*time.Millisecond, -1)
+	fc.AfterFuncNamed(time.Second, "second", func() {})
+	fc.NewTimerWithPriority(time.Second, 5)
+
+	var got []string
+	fc.SetDeliverySink(func(e WaiterInfo, _ time.Time) bool {
+		got = append(got, fmt.Sprintf("%v %d %s", e.FireAt.Sub(fc.(*fakeClock).start), e.Priority, e.Name))
+		return true
+	})
+	fc.Advance(time.Second)
+	want := []string{
+		"500ms -1 ",
+		"1s 5 ",
+		"1s 1 ",
+		"1s 0 first",
+		"1s 0 second",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got notification order %q, want %q", got, want)
+	}
+}
+
+func TestFakeClockFIFOForEqualExpirations(t *testing.T) {
+	fc := NewFakeClock()
+	var got []string
+	record := func(name string) func() {
+		return func() { got = append(got, name) }
+	}
+	a := fc.AfterFuncSync(time.Second, record("A"))
+	fc.AfterFuncSync(time.Second, record("B"))
+	fc.AfterFuncSync(time.Second, record("C"))
+	fc.Advance(time.Second)
+	if want := []string{"A", "B", "C"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got order %q, want %q", got, want)
+	}
+
+	got = nil
+	a.Reset(time.Second)
+	fc.AfterFuncSync(time.Second, record("D"))
+	b := fc.AfterFuncSync(time.Second, record("B"))
+	b.Reset(time.Second)
+	fc.Advance(time.Second)
+	if want := []string{"A", "D", "B"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got order %q after resets, want %q", got, want)
+	}
+}
+
+func TestFakeClockNextExpiration(t *testing.T) {
+	fc := NewFakeClock()
+	if next, ok := fc.NextExpiration(); ok {
+		t.Fatalf("got %v with no sleepers", next)
+	}
+	start := fc.Now()
+	fc.NewTimer(2 * time.Second)
+	fc.NewTimer(time.Second)
+	for _, want := range []time.Time{start.Add(time.Second), start.Add(2 * time.Second)} {
+		next, ok := fc.NextExpiration()
+		if !ok || !next.Equal(want) {
+			t.Fatalf("got %v (%v), want %v", next, ok, want)
+		}
+		fc.AdvanceToNextTimer()
+	}
+	if _, ok := fc.NextExpiration(); ok {
+		t.Error("got an expiration once all the timers fired")
+	}
+}
+
+func TestFakeClockSetMinDurationWarning(t *testing.T) {
+	fc := NewFakeClock()
+	fc.NewTimer(time.Nanosecond)
+	if errs := fc.Errors(); len(errs) != 0 {
+		t.Fatalf("got errors with the warning off: %v", errs)
+	}
+	fc.SetMinDurationWarning(time.Millisecond)
+	fc.NewTimer(time.Millisecond)
+	fc.NewTimer(0)
+	fc.NewTimer(-time.Second)
+	if errs := fc.Errors(); len(errs) != 0 {
+		t.Fatalf("got errors for valid durations: %v", errs)
+	}
+	timer := fc.NewTimer(time.Microsecond)
+	fc.AfterFunc(time.Microsecond, func() {})
+	timer.Reset(time.Microsecond)
+	ticker := fc.NewTicker(time.Microsecond)
+	ticker.Stop()
+	if n := len(fc.Errors()); n != 4 {
+		t.Errorf("got %d errors, want %d: %v", n, 4, fc.Errors())
+	}
+}
+
+func TestFakeClockAfterFuncSync(t *testing.T) {
+	fc := NewFakeClock()
+	var calls []string
+	fc.AfterFuncSync(time.Second, func() {
+		calls = append(calls, "first")
+		// Using the clock from the callback does not deadlock.
+		fc.AfterFuncSync(time.Second, func() { calls = append(calls, "nested") })
+	})
+	fc.AfterFuncSync(2*time.Second, func() { calls = append(calls, "second") })
+	stopped := fc.AfterFuncSync(time.Second, func() { calls = append(calls, "stopped") })
+	stopped.Stop()
+
+	withTimeout(t, time.Second, func() { fc.Advance(time.Second) })
+	if want := []string{"first"}; !reflect.DeepEqual(calls, want) {
+		t.Fatalf("got calls %q, want %q", calls, want)
+	}
+	fc.Advance(time.Second)
+	if want := []string{"first", "second", "nested"}; !reflect.DeepEqual(calls, want) {
+		t.Fatalf("got calls %q, want %q", calls, want)
+	}
+	fc.AfterFuncSync(0, func() { calls = append(calls, "now") })
+	if want := []string{"first", "second", "nested", "now"}; !reflect.DeepEqual(calls, want) {
+		t.Fatalf("got calls %q, want %q", calls, want)
+	}
+}
+
+func TestFakeClockSetAfterFuncTimeout(t *testing.T) {
+	fc := NewFakeClock()
+	fc.SetAfterFuncTimeout(10 * time.Millisecond)
+	release := make(chan struct{})
+	defer close(release)
+	var quick int32
+	fc.AfterFuncSync(time.Second, func() { <-release })
+	fc.AfterFuncSync(time.Second, func() { atomic.StoreInt32(&quick, 1) })
+	withTimeout(t, time.Second, func() { fc.Advance(time.Second) })
+	if atomic.LoadInt32(&quick) != 1 {
+		t.Error("the function after the blocking one had not returned")
+	}
+	if errs := fc.Errors(); len(errs) != 1 {
+		t.Errorf("got errors %v, want one for the blocking function", errs)
+	}
+}
+
+func TestFakeClockAfterFuncSyncConcurrentUse(t *testing.T) {
+	fc := NewFakeClock()
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	const churners = 4
+	for i := 0; i < churners; i++ {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					fc.NewTimer(time.Hour).Stop()
+				}
+			}
+		}()
+	}
+	defer func() {
+		close(stop)
+		for i := 0; i < churners; i++ {
+			<-done
+		}
+	}()
+
+	// The callbacks used to be taken by another goroutine about once in
+	// 10000 advances.
+	iterations := 20000
+	if testing.Short() {
+		iterations = 2000
+	}
+	advancer := goroutineID()
+	for i := 0; i < iterations; i++ {
+		var ranOn int64
+		fc.AfterFuncSync(time.Second, func() { atomic.StoreInt64(&ranOn, goroutineID()) })
+		fc.Advance(time.Second)
+		switch got := atomic.LoadInt64(&ranOn); got {
+		case 0:
+			t.Fatalf("iteration %d: f had not run when Advance returned", i)
+		case advancer:
+		default:
+			t.Fatalf("iteration %d: f ran on goroutine %d, not on the advancing goroutine %d", i, got, advancer)
+		}
+	}
+}
+
+func TestFakeTimerResetReturnValue(t *testing.T) {
+	fc := NewFakeClock()
+
+	pending := fc.NewTimer(time.Second)
+	if !pending.Reset(2 * time.Second) {
+		t.Error("resetting a pending timer didn't return true")
+	}
+
+	fired := fc.NewTimer(time.Second)
+	stopped := fc.NewTimer(time.Second)
+	stopped.Stop()
+	fc.Advance(time.Second)
+	<-fired.C()
+	if fired.Reset(time.Second) {
+		t.Error("resetting a fired timer didn't return false")
+	}
+	if stopped.Reset(time.Second) {
+		t.Error("resetting a stopped timer didn't return false")
+	}
+	fc.Advance(time.Second)
+	for name, timer := range map[string]Timer{"pending": pending, "fired": fired, "stopped": stopped} {
+		select {
+		case <-timer.C():
+		default:
+			t.Errorf("the %s timer didn't fire after its reset", name)
+		}
+	}
+
+	// As with the time package, a fired timer whose time was not received
+	// is reset without draining its channel, and firing it again does not
+	// block.
+	undrained := fc.NewTimer(time.Second)
+	fc.Advance(time.Second)
+	if undrained.Reset(time.Second) {
+		t.Error("resetting an undrained fired timer didn't return false")
+	}
+	withTimeout(t, time.Second, func() { fc.Advance(time.Second) })
+	select {
+	case got := <-undrained.C():
+		if want := fc.Now().Add(-time.Second); !got.Equal(want) {
+			t.Errorf("got %v, want the time of the first fire %v", got, want)
+		}
+	default:
+		t.Error("the undrained timer lost its time")
+	}
+}
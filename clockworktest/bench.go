@@ -0,0 +1,57 @@
+package clockworktest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alaingilbert/clockwork"
+)
+
+// DefaultBenchStep is the time a BenchClock advances by between two
+// iterations unless SetStep is called.
+const DefaultBenchStep = time.Millisecond
+
+// BenchClock is a FakeClock for benchmarks only, advanced in lockstep with the
+// iterations of a benchmark so that each iteration sees a deterministic,
+// increasing time and ns/op isn't polluted by the variability of the real
+// clock. Its Next method replaces the usual loop over b.N:
+//
+//	bc := clockworktest.NewBenchClock(b)
+//	for bc.Next() {
+//		cache.Get(key) // expires entries according to bc.Now()
+//	}
+type BenchClock struct {
+	clockwork.FakeClock
+	b    *testing.B
+	step time.Duration
+	i    int
+}
+
+// NewBenchClock returns a BenchClock driving the iterations of b, advancing by
+// DefaultBenchStep between them.
+func NewBenchClock(b *testing.B) *BenchClock {
+	return &BenchClock{
+		FakeClock: clockwork.NewFakeClock(),
+		b:         b,
+		step:      DefaultBenchStep,
+	}
+}
+
+// SetStep sets how far the BenchClock advances between two iterations.
+func (bc *BenchClock) SetStep(d time.Duration) {
+	bc.step = d
+}
+
+// Next reports whether the benchmark has another iteration to run, b.N in
+// total, advancing the clock by its step before every iteration but the first.
+// Sleepers expiring on the way are notified as with Advance.
+func (bc *BenchClock) Next() bool {
+	if bc.i >= bc.b.N {
+		return false
+	}
+	if bc.i > 0 {
+		bc.Advance(bc.step)
+	}
+	bc.i++
+	return true
+}
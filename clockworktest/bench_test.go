@@ -0,0 +1,28 @@
+package clockworktest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBenchClock(t *testing.T) {
+	// Next only reads b.N, so a bare testing.B with a small fixed N drives
+	// it without running a benchmark.
+	const n = 5
+	bc := NewBenchClock(&testing.B{N: n})
+	bc.SetStep(time.Second)
+	start := bc.Now()
+	iterations := 0
+	for bc.Next() {
+		if got, want := bc.Since(start), time.Duration(iterations)*time.Second; got != want {
+			t.Errorf("iteration %d: got %v since the start, want %v", iterations, got, want)
+		}
+		iterations++
+	}
+	if iterations != n {
+		t.Errorf("got %d iterations, want %d", iterations, n)
+	}
+	if bc.Next() {
+		t.Error("Next reported another iteration after the last")
+	}
+}
@@ -0,0 +1,36 @@
+// Package clockworktest provides test helpers built on top of clockwork's
+// FakeClock.
+package clockworktest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alaingilbert/clockwork"
+)
+
+// AssertAdvancedBy runs f and fails the test unless the fake clock moved by
+// exactly want while f was running. It returns whether the assertion held.
+func AssertAdvancedBy(tb testing.TB, fc clockwork.FakeClock, want time.Duration, f func()) bool {
+	tb.Helper()
+	return AssertAdvancedWithin(tb, fc, want, 0, f)
+}
+
+// AssertAdvancedWithin runs f and fails the test unless the fake clock moved by
+// want, give or take tolerance, while f was running. It returns whether the
+// assertion held.
+func AssertAdvancedWithin(tb testing.TB, fc clockwork.FakeClock, want, tolerance time.Duration, f func()) bool {
+	tb.Helper()
+	start := fc.Now()
+	f()
+	got := fc.Since(start)
+	if diff := got - want; diff < -tolerance || diff > tolerance {
+		if tolerance == 0 {
+			tb.Errorf("clock advanced by %v, want %v", got, want)
+		} else {
+			tb.Errorf("clock advanced by %v, want %v (±%v)", got, want, tolerance)
+		}
+		return false
+	}
+	return true
+}
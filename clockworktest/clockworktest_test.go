@@ -0,0 +1,49 @@
+package clockworktest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alaingilbert/clockwork"
+)
+
+// recordingTB captures failures instead of failing the enclosing test.
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertAdvancedBy(t *testing.T) {
+	fc := clockwork.NewFakeClock()
+	tb := &recordingTB{TB: t}
+	if !AssertAdvancedBy(tb, fc, 3*time.Second, func() { fc.Advance(3 * time.Second) }) {
+		t.Errorf("exact advance was reported as a failure: %v", tb.errors)
+	}
+	if AssertAdvancedBy(tb, fc, 3*time.Second, func() { fc.Advance(2 * time.Second) }) {
+		t.Errorf("short advance was not reported")
+	}
+	if len(tb.errors) != 1 {
+		t.Errorf("got %d failures, want %d", len(tb.errors), 1)
+	}
+}
+
+func TestAssertAdvancedWithin(t *testing.T) {
+	fc := clockwork.NewFakeClock()
+	tb := &recordingTB{TB: t}
+	if !AssertAdvancedWithin(tb, fc, time.Second, 100*time.Millisecond, func() { fc.Advance(950 * time.Millisecond) }) {
+		t.Errorf("advance within tolerance was reported as a failure: %v", tb.errors)
+	}
+	if AssertAdvancedWithin(tb, fc, time.Second, 100*time.Millisecond, func() { fc.Advance(1200 * time.Millisecond) }) {
+		t.Errorf("advance outside tolerance was not reported")
+	}
+	if len(tb.errors) != 1 {
+		t.Errorf("got %d failures, want %d", len(tb.errors), 1)
+	}
+}
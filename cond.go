@@ -0,0 +1,92 @@
+package clockwork
+
+import (
+	"sync"
+	"time"
+)
+
+// Cond is a condition variable, like sync.Cond, whose waits may time out
+// according to a Clock. With a FakeClock, WaitTimeout times out only when the
+// clock is advanced past its timeout.
+type Cond struct {
+	// L is held while observing or changing the condition
+	L sync.Locker
+
+	clock   Clock
+	mu      sync.Mutex
+	waiters []chan struct{}
+}
+
+// NewCond returns a new Cond with Locker l, which times out waits using clock.
+func NewCond(clock Clock, l sync.Locker) *Cond {
+	return &Cond{L: l, clock: clock}
+}
+
+// Wait atomically unlocks c.L and suspends the calling goroutine until it is
+// woken by Signal or Broadcast, then locks c.L again before returning.
+func (c *Cond) Wait() {
+	ch := c.enqueue()
+	c.L.Unlock()
+	<-ch
+	c.L.Lock()
+}
+
+// WaitTimeout is like Wait, but gives up waiting once d has elapsed on the
+// clock. It returns true if the goroutine was woken by Signal or Broadcast,
+// false if it timed out. c.L is locked again in both cases.
+func (c *Cond) WaitTimeout(d time.Duration) bool {
+	ch := c.enqueue()
+	c.L.Unlock()
+	defer c.L.Lock()
+	timer := c.clock.NewTimer(d)
+	select {
+	case <-ch:
+		timer.Stop()
+		return true
+	case <-timer.C():
+		// A wake up may have raced with the timeout; it wins if the waiter
+		// is no longer queued.
+		return !c.dequeue(ch)
+	}
+}
+
+// Signal wakes one goroutine waiting on c, if there is any.
+func (c *Cond) Signal() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.waiters) > 0 {
+		close(c.waiters[0])
+		c.waiters = c.waiters[1:]
+	}
+}
+
+// Broadcast wakes all goroutines waiting on c.
+func (c *Cond) Broadcast() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, ch := range c.waiters {
+		close(ch)
+	}
+	c.waiters = nil
+}
+
+func (c *Cond) enqueue() chan struct{} {
+	ch := make(chan struct{})
+	c.mu.Lock()
+	c.waiters = append(c.waiters, ch)
+	c.mu.Unlock()
+	return ch
+}
+
+// dequeue removes ch from the waiters, reporting whether it was still there.
+func (c *Cond) dequeue(ch chan struct{}) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, w := range c.waiters {
+		if w == ch {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
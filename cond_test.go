@@ -0,0 +1,93 @@
+package clockwork
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCondWaitTimeoutExpires(t *testing.T) {
+	fc := NewFakeClock()
+	var mu sync.Mutex
+	c := NewCond(fc, &mu)
+
+	result := make(chan bool)
+	go func() {
+		mu.Lock()
+		defer mu.Unlock()
+		result <- c.WaitTimeout(time.Second)
+	}()
+	fc.BlockUntil(1)
+	fc.Advance(999 * time.Millisecond)
+	select {
+	case <-result:
+		t.Fatalf("WaitTimeout returned before its timeout")
+	default:
+	}
+	fc.Advance(time.Millisecond)
+	select {
+	case signaled := <-result:
+		if signaled {
+			t.Errorf("WaitTimeout reported a signal, want a timeout")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for WaitTimeout to return")
+	}
+}
+
+func TestCondWaitTimeoutSignaled(t *testing.T) {
+	fc := NewFakeClock()
+	var mu sync.Mutex
+	c := NewCond(fc, &mu)
+
+	result := make(chan bool)
+	go func() {
+		mu.Lock()
+		defer mu.Unlock()
+		result <- c.WaitTimeout(time.Second)
+	}()
+	fc.BlockUntil(1)
+	mu.Lock()
+	c.Signal()
+	mu.Unlock()
+	select {
+	case signaled := <-result:
+		if !signaled {
+			t.Errorf("WaitTimeout reported a timeout, want a signal")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for WaitTimeout to return")
+	}
+	// The timeout timer was released.
+	fc.BlockUntil(0)
+}
+
+func TestCondBroadcast(t *testing.T) {
+	fc := NewFakeClock()
+	var mu sync.Mutex
+	c := NewCond(fc, &mu)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			mu.Lock()
+			defer mu.Unlock()
+			c.Wait()
+		}()
+	}
+	withTimeout(t, time.Second, func() {
+		for {
+			c.mu.Lock()
+			n := len(c.waiters)
+			c.mu.Unlock()
+			if n == 3 {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		c.Broadcast()
+		wg.Wait()
+	})
+}
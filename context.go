@@ -0,0 +1,162 @@
+package clockwork
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// deadlineContext is a context canceled when a deadline expires on a Clock,
+// rather than on the real time like the contexts of the context package.
+type deadlineContext struct {
+	context.Context // the parent, for Value
+
+	done chan struct{}
+
+	mu       sync.Mutex
+	deadline time.Time
+	err      error
+}
+
+// newDeadlineContext returns a deadlineContext with the given deadline, which
+// is canceled when its parent is. It is up to the caller to cancel it with
+// context.DeadlineExceeded when the deadline expires.
+func newDeadlineContext(parent context.Context, deadline time.Time) *deadlineContext {
+	ctx := &deadlineContext{
+		Context:  parent,
+		done:     make(chan struct{}),
+		deadline: deadline,
+	}
+	if parent.Done() != nil {
+		go func() {
+			select {
+			case <-parent.Done():
+				ctx.cancel(parent.Err())
+			case <-ctx.done:
+			}
+		}()
+	}
+	return ctx
+}
+
+func (ctx *deadlineContext) Deadline() (time.Time, bool) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	return ctx.deadline, true
+}
+
+func (ctx *deadlineContext) Done() <-chan struct{} {
+	return ctx.done
+}
+
+func (ctx *deadlineContext) Err() error {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	return ctx.err
+}
+
+// cancel cancels the context with err, unless it already is.
+func (ctx *deadlineContext) cancel(err error) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	if ctx.err != nil {
+		return
+	}
+	ctx.err = err
+	close(ctx.done)
+}
+
+// contextTimer is the Timer returned by TimerContext, which cancels its
+// context when it fires.
+type contextTimer struct {
+	Timer // the AfterFunc timer doing the work
+	c     chan time.Time
+	clock Clock
+	ctx   *deadlineContext
+}
+
+func (t *contextTimer) C() <-chan time.Time { return t.c }
+
+// Reset re-arms the timer and moves the deadline of its context accordingly,
+// unless the context is already done.
+func (t *contextTimer) Reset(d time.Duration) bool {
+	t.ctx.mu.Lock()
+	if t.ctx.err == nil {
+		t.ctx.deadline = t.clock.Now().Add(d)
+	}
+	t.ctx.mu.Unlock()
+	return t.Timer.Reset(d)
+}
+
+// timerContext implements TimerContext for clock, afterFunc being the
+// function scheduling the timer.
+func timerContext(clock Clock, afterFunc func(time.Duration, func()) Timer, parent context.Context, d time.Duration) (Timer, context.Context, context.CancelFunc) {
+	t := &contextTimer{
+		c:     make(chan time.Time, 1),
+		clock: clock,
+		ctx:   newDeadlineContext(parent, clock.Now().Add(d)),
+	}
+	t.Timer = afterFunc(d, func() {
+		select {
+		case t.c <- clock.Now():
+		default:
+		}
+		t.ctx.cancel(context.DeadlineExceeded)
+	})
+	cancel := func() {
+		t.Timer.Stop()
+		t.ctx.cancel(context.Canceled)
+	}
+	return t, t.ctx, cancel
+}
+
+// TimerContext returns a Timer firing after d along with a context derived
+// from parent whose deadline is the expiration of the timer: when the timer
+// fires, it sends the time on its channel and cancels the context with
+// context.DeadlineExceeded, so that a single object serves as the deadline of
+// an operation and the way to observe it. Stopping the timer keeps the context
+// from expiring; resetting it moves the deadline of the context. The cancel
+// function stops the timer and cancels the context, and should be called once
+// the operation is done, as for context.WithTimeout.
+func (rc *realClock) TimerContext(parent context.Context, d time.Duration) (Timer, context.Context, context.CancelFunc) {
+	return timerContext(rc, rc.AfterFunc, parent, d)
+}
+
+// TimerContext returns a Timer and a context expiring together once d has
+// elapsed on the fakeClock, see the realClock version. The timer fires and the
+// context is canceled by the time the advance reaching d returns.
+func (fc *fakeClock) TimerContext(parent context.Context, d time.Duration) (Timer, context.Context, context.CancelFunc) {
+	return timerContext(fc, fc.AfterFuncSync, parent, d)
+}
+
+// WithDeadline returns a copy of parent which is canceled once clock reaches
+// deadline, rather than once the real time does as with context.WithDeadline.
+// On a FakeClock, the context only expires when the clock is advanced past the
+// deadline, which makes timeouts deterministic in tests. Its Err is then
+// context.DeadlineExceeded. The context is also canceled when parent is, or
+// when the returned CancelFunc is called, which stops the underlying timer so
+// that it doesn't linger as a sleeper. The deadline of parent, if any, is
+// measured on the real time and is not compared to the one on clock.
+func WithDeadline(parent context.Context, clock Clock, deadline time.Time) (context.Context, context.CancelFunc) {
+	ctx := newDeadlineContext(parent, deadline)
+	d := clock.Until(deadline)
+	if d <= 0 {
+		ctx.cancel(context.DeadlineExceeded)
+		return ctx, func() { ctx.cancel(context.Canceled) }
+	}
+	t := clock.AfterFunc(d, func() { ctx.cancel(context.DeadlineExceeded) })
+	go func() {
+		// Don't leave the timer behind when parent is canceled.
+		<-ctx.Done()
+		t.Stop()
+	}()
+	return ctx, func() {
+		t.Stop()
+		ctx.cancel(context.Canceled)
+	}
+}
+
+// WithTimeout returns WithDeadline(parent, clock, clock.Now().Add(timeout)).
+func WithTimeout(parent context.Context, clock Clock, timeout time.Duration) (context.Context, context.CancelFunc) {
+	return WithDeadline(parent, clock, clock.Now().Add(timeout))
+}
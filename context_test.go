@@ -0,0 +1,202 @@
+package clockwork
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFakeClockTimerContext(t *testing.T) {
+	fc := NewFakeClock()
+	timer, ctx, cancel := fc.TimerContext(context.Background(), time.Second)
+	defer cancel()
+	if deadline, ok := ctx.Deadline(); !ok || !deadline.Equal(fc.Now().Add(time.Second)) {
+		t.Errorf("got deadline %v (%v), want %v", deadline, ok, fc.Now().Add(time.Second))
+	}
+	fc.Advance(999 * time.Millisecond)
+	if err := ctx.Err(); err != nil {
+		t.Fatalf("context done before the deadline: %v", err)
+	}
+	select {
+	case <-timer.C():
+		t.Fatal("timer fired before the deadline")
+	default:
+	}
+
+	fc.Advance(time.Millisecond)
+	select {
+	case got := <-timer.C():
+		if !got.Equal(fc.Now()) {
+			t.Errorf("got %v, want %v", got, fc.Now())
+		}
+	default:
+		t.Error("timer did not fire at the deadline")
+	}
+	select {
+	case <-ctx.Done():
+		if err := ctx.Err(); err != context.DeadlineExceeded {
+			t.Errorf("got %v, want %v", err, context.DeadlineExceeded)
+		}
+	default:
+		t.Error("context was not canceled at the deadline")
+	}
+}
+
+func TestFakeClockTimerContextCancel(t *testing.T) {
+	fc := NewFakeClock()
+	parent, cancelParent := context.WithCancel(context.Background())
+	type key struct{}
+	parent = context.WithValue(parent, key{}, "value")
+	timer, ctx, cancel := fc.TimerContext(parent, time.Second)
+	defer cancel()
+	if v := ctx.Value(key{}); v != "value" {
+		t.Errorf("got value %v, want %q", v, "value")
+	}
+	cancelParent()
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("context not canceled with its parent")
+	}
+	if err := ctx.Err(); err != context.Canceled {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+
+	timer, ctx, cancel = fc.TimerContext(context.Background(), time.Second)
+	cancel()
+	if err := ctx.Err(); err != context.Canceled {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+	fc.Advance(time.Second)
+	select {
+	case <-timer.C():
+		t.Error("timer fired after cancel")
+	default:
+	}
+}
+
+func TestRealClockTimerContext(t *testing.T) {
+	timer, ctx, cancel := NewRealClock().TimerContext(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	select {
+	case <-timer.C():
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the timer")
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the context")
+	}
+	if err := ctx.Err(); err != context.DeadlineExceeded {
+		t.Errorf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestFakeClockNewTimerContext(t *testing.T) {
+	fc := NewFakeClock()
+	ctx, cancel := context.WithCancel(context.Background())
+	timer := fc.NewTimerContext(ctx, time.Second)
+	if n := fc.WaiterCount(); n != 1 {
+		t.Fatalf("got %d sleepers, want %d", n, 1)
+	}
+	cancel()
+	withTimeout(t, time.Second, func() { fc.BlockUntil(0) })
+	fc.Advance(time.Second)
+	select {
+	case <-timer.C():
+		t.Fatal("timer fired after its context was canceled")
+	default:
+	}
+	if timer.Stop() {
+		t.Error("stopping a timer stopped by its context returned true")
+	}
+
+	// Already done, the timer is never armed.
+	timer = fc.NewTimerContext(ctx, time.Second)
+	if n := fc.WaiterCount(); n != 0 {
+		t.Errorf("got %d sleepers with a done context, want none", n)
+	}
+
+	// Firing, then canceling, is harmless.
+	ctx, cancel = context.WithCancel(context.Background())
+	timer = fc.NewTimerContext(ctx, time.Second)
+	fc.Advance(time.Second)
+	cancel()
+	select {
+	case <-timer.C():
+	default:
+		t.Error("timer did not fire")
+	}
+}
+
+func TestRealClockNewTimerContext(t *testing.T) {
+	rc := NewRealClock()
+	timer := rc.NewTimerContext(context.Background(), time.Millisecond)
+	select {
+	case <-timer.C():
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the timer")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	timer = rc.NewTimerContext(ctx, 50*time.Millisecond)
+	cancel()
+	select {
+	case <-timer.C():
+		t.Error("timer fired after its context was canceled")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	fc := NewFakeClock()
+	ctx, cancel := WithTimeout(context.Background(), fc, time.Second)
+	defer cancel()
+	if deadline, ok := ctx.Deadline(); !ok || !deadline.Equal(fc.Now().Add(time.Second)) {
+		t.Errorf("got deadline %v (%v), want %v", deadline, ok, fc.Now().Add(time.Second))
+	}
+	fc.BlockUntil(1)
+	fc.Advance(999 * time.Millisecond)
+	select {
+	case <-ctx.Done():
+		t.Fatal("context done before the deadline")
+	case <-time.After(10 * time.Millisecond):
+	}
+	fc.Advance(time.Millisecond)
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("context not done at the deadline")
+	}
+	if err := ctx.Err(); err != context.DeadlineExceeded {
+		t.Errorf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestWithDeadlineCancel(t *testing.T) {
+	fc := NewFakeClock()
+	ctx, cancel := WithDeadline(context.Background(), fc, fc.Now().Add(time.Second))
+	cancel()
+	if err := ctx.Err(); err != context.Canceled {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+	if n := fc.WaiterCount(); n != 0 {
+		t.Errorf("got %d sleepers after cancel, want none", n)
+	}
+
+	parent, cancelParent := context.WithCancel(context.Background())
+	ctx, cancel = WithDeadline(parent, fc, fc.Now().Add(time.Second))
+	defer cancel()
+	cancelParent()
+	withTimeout(t, time.Second, func() { fc.BlockUntil(0) })
+	if err := ctx.Err(); err != context.Canceled {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+
+	ctx, cancel = WithDeadline(context.Background(), fc, fc.Now())
+	defer cancel()
+	if err := ctx.Err(); err != context.DeadlineExceeded {
+		t.Errorf("got %v for a past deadline, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,139 @@
+package clockwork
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CountingClock is a Clock counting the calls made to it, to assert how often
+// the code under test reads the time or schedules work.
+type CountingClock interface {
+	Clock
+	// Calls returns how many times the method of the given name, e.g.
+	// "Now", was called.
+	Calls(method string) int
+}
+
+// countingClock counts the calls to the methods of its base clock.
+type countingClock struct {
+	Clock // the base clock, doing the actual work
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+// NewCountingClock returns a clock counting the calls to its methods, which
+// base carries out. A method implemented by base on top of another, such as
+// Since on top of Now, counts once under its own name.
+func NewCountingClock(base Clock) CountingClock {
+	return &countingClock{Clock: base, calls: make(map[string]int)}
+}
+
+// Calls returns how many times the named method was called. It is safe to
+// call concurrently with the other methods.
+func (cc *countingClock) Calls(method string) int {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return cc.calls[method]
+}
+
+func (cc *countingClock) count(method string) {
+	cc.mu.Lock()
+	cc.calls[method]++
+	cc.mu.Unlock()
+}
+
+// Capabilities describes the base clock, except that the countingClock is not
+// a FakeClock.
+func (cc *countingClock) Capabilities() ClockCapabilities {
+	cc.count("Capabilities")
+	c := cc.Clock.Capabilities()
+	c.Fake = false
+	c.Features &^= FeatureAdvance
+	return c
+}
+
+func (cc *countingClock) After(d time.Duration) <-chan time.Time {
+	cc.count("After")
+	return cc.Clock.After(d)
+}
+
+func (cc *countingClock) Sleep(d time.Duration) {
+	cc.count("Sleep")
+	cc.Clock.Sleep(d)
+}
+
+func (cc *countingClock) Now() time.Time {
+	cc.count("Now")
+	return cc.Clock.Now()
+}
+
+func (cc *countingClock) Since(t time.Time) time.Duration {
+	cc.count("Since")
+	return cc.Clock.Since(t)
+}
+
+func (cc *countingClock) Until(t time.Time) time.Duration {
+	cc.count("Until")
+	return cc.Clock.Until(t)
+}
+
+func (cc *countingClock) NewTicker(d time.Duration) Ticker {
+	cc.count("NewTicker")
+	return cc.Clock.NewTicker(d)
+}
+
+func (cc *countingClock) NewTimer(d time.Duration) Timer {
+	cc.count("NewTimer")
+	return cc.Clock.NewTimer(d)
+}
+
+func (cc *countingClock) AfterFunc(d time.Duration, f func()) Timer {
+	cc.count("AfterFunc")
+	return cc.Clock.AfterFunc(d, f)
+}
+
+func (cc *countingClock) Location() *time.Location {
+	cc.count("Location")
+	return cc.Clock.Location()
+}
+
+func (cc *countingClock) TruncateToWindow(t time.Time, window time.Duration) time.Time {
+	cc.count("TruncateToWindow")
+	return cc.Clock.TruncateToWindow(t, window)
+}
+
+func (cc *countingClock) Parse(layout, value string) (time.Time, error) {
+	cc.count("Parse")
+	return cc.Clock.Parse(layout, value)
+}
+
+func (cc *countingClock) SleepContext(ctx context.Context, d time.Duration) error {
+	cc.count("SleepContext")
+	return cc.Clock.SleepContext(ctx, d)
+}
+
+func (cc *countingClock) AtLeast(d time.Duration, f func()) {
+	cc.count("AtLeast")
+	cc.Clock.AtLeast(d, f)
+}
+
+func (cc *countingClock) TimerContext(parent context.Context, d time.Duration) (Timer, context.Context, context.CancelFunc) {
+	cc.count("TimerContext")
+	return cc.Clock.TimerContext(parent, d)
+}
+
+func (cc *countingClock) NewTickerFunc(d time.Duration, f func()) Ticker {
+	cc.count("NewTickerFunc")
+	return cc.Clock.NewTickerFunc(d, f)
+}
+
+func (cc *countingClock) NewTimerContext(ctx context.Context, d time.Duration) Timer {
+	cc.count("NewTimerContext")
+	return cc.Clock.NewTimerContext(ctx, d)
+}
+
+func (cc *countingClock) Tick(d time.Duration) <-chan time.Time {
+	cc.count("Tick")
+	return cc.Clock.Tick(d)
+}
@@ -0,0 +1,35 @@
+package clockwork
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCountingClock(t *testing.T) {
+	fc := NewFakeClock()
+	cc := NewCountingClock(fc)
+	start := cc.Now()
+	cc.Now()
+	if got := cc.Since(start); got != 0 {
+		t.Errorf("got Since %v, want 0", got)
+	}
+	timer := cc.NewTimer(time.Second)
+	fc.Advance(time.Second)
+	select {
+	case <-timer.C():
+	default:
+		t.Error("timer did not fire with the base clock")
+	}
+	for method, want := range map[string]int{"Now": 2, "Since": 1, "NewTimer": 1, "Sleep": 0} {
+		if got := cc.Calls(method); got != want {
+			t.Errorf("got %d calls to %s, want %d", got, method, want)
+		}
+	}
+	cc.Tick(time.Second)
+	if got := cc.Calls("Tick"); got != 1 {
+		t.Errorf("got %d calls to Tick, want 1", got)
+	}
+	if caps := cc.Capabilities(); caps.Fake || caps.Features.Has(FeatureAdvance) {
+		t.Errorf("counting clock reports being fake: %+v", caps)
+	}
+}
@@ -0,0 +1,191 @@
+package clockwork
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CronScheduler runs jobs on cron schedules. It tells the time through a
+// Clock, so that with a FakeClock jobs run deterministically as the clock is
+// advanced across their scheduled instants.
+type CronScheduler struct {
+	clock  Clock
+	ticker Ticker
+	done   chan struct{}
+
+	mu     sync.Mutex
+	jobs   []*cronJob
+	nextID int
+	last   time.Time // last minute which was checked for due jobs
+}
+
+type cronJob struct {
+	id       int
+	schedule *cronSchedule
+	f        func()
+}
+
+// NewCron returns a CronScheduler which checks every minute of the given clock
+// for due jobs. Call Stop to release its ticker.
+func NewCron(clock Clock) *CronScheduler {
+	c := &CronScheduler{
+		clock:  clock,
+		ticker: clock.NewTicker(time.Minute),
+		done:   make(chan struct{}),
+		last:   clock.Now().Truncate(time.Minute),
+	}
+	go c.loop()
+	return c
+}
+
+// Add schedules f according to spec, a standard five field cron expression
+// (minute, hour, day of month, month and day of week) made of numbers, ranges,
+// lists, steps and wildcards, e.g. "0 0 * * *" or "*/15 9-17 * * 1-5". It
+// returns an identifier for the job.
+func (c *CronScheduler) Add(spec string, f func()) (id int, err error) {
+	schedule, err := parseCronSpec(spec)
+	if err != nil {
+		return 0, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nextID++
+	c.jobs = append(c.jobs, &cronJob{id: c.nextID, schedule: schedule, f: f})
+	return c.nextID, nil
+}
+
+// Stop stops the scheduler from running any more jobs.
+func (c *CronScheduler) Stop() {
+	c.ticker.Stop()
+	close(c.done)
+}
+
+func (c *CronScheduler) loop() {
+	for {
+		select {
+		case <-c.ticker.Chan():
+			c.runDue()
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// runDue runs the jobs due at each minute elapsed since the last check. Jobs
+// run one after another, in chronological order, then in the order they were
+// added.
+func (c *CronScheduler) runDue() {
+	var due []func()
+	c.mu.Lock()
+	now := c.clock.Now().Truncate(time.Minute)
+	for m := c.last.Add(time.Minute); !m.After(now); m = m.Add(time.Minute) {
+		for _, job := range c.jobs {
+			if job.schedule.matches(m) {
+				due = append(due, job.f)
+			}
+		}
+	}
+	if now.After(c.last) {
+		c.last = now
+	}
+	c.mu.Unlock()
+	for _, f := range due {
+		f()
+	}
+}
+
+// cronSchedule holds the allowed values of each cron field as bit sets.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// domAny and dowAny record wildcard day fields, since when both day
+	// fields are restricted a day matching either of them is due
+	domAny, dowAny bool
+}
+
+func (s *cronSchedule) matches(t time.Time) bool {
+	if s.minute&(1<<uint(t.Minute())) == 0 ||
+		s.hour&(1<<uint(t.Hour())) == 0 ||
+		s.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+func parseCronSpec(spec string) (*cronSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("clockwork: cron spec %q must have 5 fields, has %d", spec, len(fields))
+	}
+	var s cronSchedule
+	var err error
+	if s.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, err
+	}
+	if s.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, err
+	}
+	// 7 is an alias for Sunday
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domAny = strings.HasPrefix(fields[2], "*")
+	s.dowAny = strings.HasPrefix(fields[4], "*")
+	return &s, nil
+}
+
+// parseCronField parses a comma separated list of "*", "n" or "n-m" items,
+// each optionally followed by "/step", into a bit set of the allowed values.
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rng, step := item, 1
+		if i := strings.IndexByte(item, '/'); i >= 0 {
+			var err error
+			rng = item[:i]
+			if step, err = strconv.Atoi(item[i+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("clockwork: invalid step in cron field %q", field)
+			}
+		}
+		lo, hi := min, max
+		if rng != "*" {
+			var err error
+			bounds := strings.SplitN(rng, "-", 2)
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("clockwork: invalid value in cron field %q", field)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("clockwork: invalid range in cron field %q", field)
+				}
+			} else if step > 1 {
+				// "n/step" means from n to the maximum
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("clockwork: cron field %q out of range [%d, %d]", field, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
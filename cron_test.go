@@ -0,0 +1,103 @@
+package clockwork
+
+import (
+	"testing"
+	"time"
+)
+
+// expectRuns waits for n values on ran, then makes sure no more follow.
+func expectRuns(t *testing.T, ran <-chan time.Time, n int) []time.Time {
+	t.Helper()
+	var got []time.Time
+	for i := 0; i < n; i++ {
+		select {
+		case at := <-ran:
+			got = append(got, at)
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for run %d of %d", i+1, n)
+		}
+	}
+	select {
+	case at := <-ran:
+		t.Fatalf("unexpected extra run at %v", at)
+	case <-time.After(10 * time.Millisecond):
+	}
+	return got
+}
+
+func TestCronDaily(t *testing.T) {
+	fc := NewFakeClock()
+	start := fc.Now()
+	cron := NewCron(fc)
+	defer cron.Stop()
+	ran := make(chan time.Time, 10)
+	if _, err := cron.Add("0 0 * * *", func() { ran <- fc.Now() }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fc.BlockUntil(1)
+
+	// The start instant itself is not run.
+	fc.Advance(23 * time.Hour)
+	fc.BlockUntil(1)
+	expectRuns(t, ran, 0)
+
+	fc.Advance(time.Hour)
+	fc.BlockUntil(1)
+	expectRuns(t, ran, 1)
+
+	// Crossing two more daily boundaries at once runs the job twice.
+	fc.Advance(48 * time.Hour)
+	fc.BlockUntil(1)
+	got := expectRuns(t, ran, 2)
+	if want := start.Add(72 * time.Hour); !got[1].Equal(want) {
+		t.Errorf("job observed the wrong time, got: %v, want: %v", got[1], want)
+	}
+}
+
+func TestCronSchedules(t *testing.T) {
+	fc := NewFakeClock() // Wednesday April 4th 1984, midnight
+	cron := NewCron(fc)
+	defer cron.Stop()
+	ran := make(chan time.Time, 100)
+	for _, spec := range []string{
+		"30 12 * * *",     // every day at 12:30
+		"*/15 9-10 * * *", // every quarter hour from 9:00 to 10:45
+		"0 0 5 * *",       // on the 5th
+		"0 6 * * 4",       // on Thursdays at 6:00
+	} {
+		if _, err := cron.Add(spec, func() { ran <- fc.Now() }); err != nil {
+			t.Fatalf("unexpected error for %q: %v", spec, err)
+		}
+	}
+	fc.BlockUntil(1)
+	fc.Advance(24 * time.Hour)
+	fc.BlockUntil(1)
+	// 8 quarter hours, 12:30, then the 5th at midnight.
+	expectRuns(t, ran, 10)
+	fc.Advance(7 * time.Hour)
+	fc.BlockUntil(1)
+	// Thursday at 6:00
+	expectRuns(t, ran, 1)
+}
+
+func TestCronInvalidSpecs(t *testing.T) {
+	fc := NewFakeClock()
+	cron := NewCron(fc)
+	defer cron.Stop()
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+	} {
+		if _, err := cron.Add(spec, func() {}); err == nil {
+			t.Errorf("invalid spec %q was accepted", spec)
+		}
+	}
+}
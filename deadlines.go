@@ -0,0 +1,97 @@
+package clockwork
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DeadlineGroup tracks several deadlines on a Clock and reports which expires
+// first.
+type DeadlineGroup struct {
+	clock Clock
+	// fired is signaled whenever one of the deadlines expires
+	fired chan struct{}
+
+	mu        sync.Mutex
+	deadlines []*groupDeadline
+}
+
+type groupDeadline struct {
+	at       time.Time
+	timer    Timer
+	reported bool
+}
+
+// NewDeadlineGroup returns an empty DeadlineGroup running on clock.
+func NewDeadlineGroup(clock Clock) *DeadlineGroup {
+	return &DeadlineGroup{
+		clock: clock,
+		fired: make(chan struct{}, 1),
+	}
+}
+
+// Add adds a deadline expiring after d and returns its id. Ids are assigned in
+// order, starting at 0.
+func (g *DeadlineGroup) Add(d time.Duration) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	dl := &groupDeadline{at: g.clock.Now().Add(d)}
+	dl.timer = g.clock.AfterFunc(d, g.notify)
+	g.deadlines = append(g.deadlines, dl)
+	return len(g.deadlines) - 1
+}
+
+// Wait blocks until one of the deadlines expires and returns its id. When
+// several deadlines have expired, the earliest is reported first, then the one
+// added first. Each deadline is reported once, so successive calls return the
+// expired deadlines in order. Wait returns -1 and the context's error if the
+// context is done first.
+func (g *DeadlineGroup) Wait(ctx context.Context) (id int, err error) {
+	for {
+		if id, ok := g.nextExpired(); ok {
+			return id, nil
+		}
+		select {
+		case <-g.fired:
+		case <-ctx.Done():
+			return -1, ctx.Err()
+		}
+	}
+}
+
+// Stop stops the timers of all the deadlines which have yet to expire.
+func (g *DeadlineGroup) Stop() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, dl := range g.deadlines {
+		dl.timer.Stop()
+	}
+}
+
+func (g *DeadlineGroup) notify() {
+	select {
+	case g.fired <- struct{}{}:
+	default:
+	}
+}
+
+func (g *DeadlineGroup) nextExpired() (int, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := g.clock.Now()
+	id := -1
+	for i, dl := range g.deadlines {
+		if dl.reported || dl.at.After(now) {
+			continue
+		}
+		if id < 0 || dl.at.Before(g.deadlines[id].at) {
+			id = i
+		}
+	}
+	if id < 0 {
+		return -1, false
+	}
+	g.deadlines[id].reported = true
+	return id, true
+}
@@ -0,0 +1,64 @@
+package clockwork
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDeadlineGroupEarliestWins(t *testing.T) {
+	fc := NewFakeClock()
+	g := NewDeadlineGroup(fc)
+	defer g.Stop()
+	for _, d := range []time.Duration{3 * time.Second, time.Second, 2 * time.Second, time.Second} {
+		g.Add(d)
+	}
+
+	type result struct {
+		id  int
+		err error
+	}
+	results := make(chan result)
+	go func() {
+		id, err := g.Wait(context.Background())
+		results <- result{id, err}
+	}()
+	fc.BlockUntil(4)
+	fc.Advance(999 * time.Millisecond)
+	select {
+	case r := <-results:
+		t.Fatalf("Wait returned before any deadline expired: %v", r)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// All deadlines expire at once, they are reported by expiration then by
+	// id.
+	fc.Advance(5 * time.Second)
+	select {
+	case r := <-results:
+		if r.err != nil || r.id != 1 {
+			t.Errorf("got id %d (%v), want %d", r.id, r.err, 1)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for the first deadline")
+	}
+	for _, want := range []int{3, 2, 0} {
+		id, err := g.Wait(context.Background())
+		if err != nil || id != want {
+			t.Errorf("got id %d (%v), want %d", id, err, want)
+		}
+	}
+}
+
+func TestDeadlineGroupContext(t *testing.T) {
+	fc := NewFakeClock()
+	g := NewDeadlineGroup(fc)
+	g.Add(time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if id, err := g.Wait(ctx); id != -1 || err != context.Canceled {
+		t.Errorf("got id %d (%v), want %d (%v)", id, err, -1, context.Canceled)
+	}
+	g.Stop()
+	fc.BlockUntil(0)
+}
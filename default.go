@@ -0,0 +1,49 @@
+package clockwork
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	defaultMu    sync.RWMutex
+	defaultClock Clock = NewRealClock()
+)
+
+// Default returns the package-level Clock, used by Now, After and Sleep. It is
+// a real clock unless replaced with SetDefault. It serves code which cannot
+// easily be handed a Clock; code which can should rather take one.
+func Default() Clock {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultClock
+}
+
+// SetDefault replaces the package-level Clock returned by Default, for
+// instance with a FakeClock for the duration of a test; nil restores a real
+// clock. The package-level Clock is global state: tests replacing it must not
+// run in parallel with tests using it.
+func SetDefault(c Clock) {
+	if c == nil {
+		c = NewRealClock()
+	}
+	defaultMu.Lock()
+	defaultClock = c
+	defaultMu.Unlock()
+}
+
+// Now returns the current time of the package-level Clock.
+func Now() time.Time {
+	return Default().Now()
+}
+
+// After waits for the duration to elapse on the package-level Clock and then
+// sends the current time on the returned channel.
+func After(d time.Duration) <-chan time.Time {
+	return Default().After(d)
+}
+
+// Sleep blocks until the duration has passed on the package-level Clock.
+func Sleep(d time.Duration) {
+	Default().Sleep(d)
+}
@@ -0,0 +1,37 @@
+package clockwork
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDefault(t *testing.T) {
+	if caps := Default().Capabilities(); caps.Fake {
+		t.Fatal("the default clock is not a real clock")
+	}
+	fc := NewFakeClock()
+	SetDefault(fc)
+	defer SetDefault(nil)
+	if Default() != fc {
+		t.Fatal("SetDefault did not replace the default clock")
+	}
+	if !Now().Equal(fc.Now()) {
+		t.Errorf("got %v, want %v", Now(), fc.Now())
+	}
+
+	c := After(time.Second)
+	slept := make(chan struct{})
+	go func() {
+		Sleep(time.Second)
+		close(slept)
+	}()
+	fc.BlockUntil(2)
+	fc.Advance(time.Second)
+	<-c
+	<-slept
+
+	SetDefault(nil)
+	if Default().Capabilities().Fake {
+		t.Error("SetDefault(nil) did not restore a real clock")
+	}
+}
@@ -0,0 +1,33 @@
+package clockwork
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// CompareFires compares fires recorded with FakeClock.RecordFires against a
+// golden recording. It returns nil when they match, otherwise an error
+// describing the first difference.
+func CompareFires(got, golden io.Reader) error {
+	g, w := bufio.NewScanner(got), bufio.NewScanner(golden)
+	for line := 1; ; line++ {
+		gotOK, wantOK := g.Scan(), w.Scan()
+		if err := g.Err(); err != nil {
+			return fmt.Errorf("clockwork: reading recorded fires: %v", err)
+		}
+		if err := w.Err(); err != nil {
+			return fmt.Errorf("clockwork: reading golden fires: %v", err)
+		}
+		switch {
+		case !gotOK && !wantOK:
+			return nil
+		case !gotOK:
+			return fmt.Errorf("clockwork: fire %d is missing, want %q", line, w.Text())
+		case !wantOK:
+			return fmt.Errorf("clockwork: unexpected fire %d %q", line, g.Text())
+		case g.Text() != w.Text():
+			return fmt.Errorf("clockwork: fire %d differs, got %q, want %q", line, g.Text(), w.Text())
+		}
+	}
+}
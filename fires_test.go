@@ -0,0 +1,49 @@
+package clockwork
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordFires(t *testing.T) {
+	fc := NewFakeClockAt(time.Date(1999, time.February, 3, 4, 5, 6, 0, time.UTC))
+	var got bytes.Buffer
+	fc.RecordFires(&got)
+	fc.NewTimer(time.Second)
+	fc.AfterFunc(2*time.Second, func() {})
+	fc.AfterFuncNamed(2*time.Second, "cleanup job", func() {})
+	stopped := fc.NewTimer(time.Second)
+	stopped.Stop()
+	fc.Advance(time.Second)
+	fc.Advance(time.Second)
+	fc.RecordFires(nil)
+	fc.NewTimer(0)
+
+	golden := "1999-02-03T04:05:07Z timer\n" +
+		"1999-02-03T04:05:08Z afterfunc\n" +
+		"1999-02-03T04:05:08Z afterfunc \"cleanup job\"\n"
+	if err := CompareFires(&got, strings.NewReader(golden)); err != nil {
+		t.Errorf("unexpected difference: %v\n%s", err, got.String())
+	}
+}
+
+func TestCompareFires(t *testing.T) {
+	for _, tc := range []struct {
+		got, golden string
+		match       bool
+	}{
+		{"", "", true},
+		{"a\nb\n", "a\nb\n", true},
+		{"a\nb", "a\nb\n", true},
+		{"a\nc\n", "a\nb\n", false},
+		{"a\n", "a\nb\n", false},
+		{"a\nb\n", "a\n", false},
+	} {
+		err := CompareFires(strings.NewReader(tc.got), strings.NewReader(tc.golden))
+		if (err == nil) != tc.match {
+			t.Errorf("CompareFires(%q, %q) = %v, want match: %v", tc.got, tc.golden, err, tc.match)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package clockwork
+
+import (
+	"time"
+)
+
+// locatedClock is a view of a FakeClock reporting its time in another
+// location.
+type locatedClock struct {
+	FakeClock // the viewed clock, holding the time and the waiters
+	loc       *time.Location
+}
+
+// In returns a view of the fakeClock reporting its time in loc, like
+// time.Time.In. The view shares the time and the waiters of the fakeClock:
+// advancing either of them moves both, and the timers and tickers created
+// through the view are those of the fakeClock, notified with times in its own
+// location. Only Now, Since, Until, Location, Parse and Capabilities differ.
+// Like time.Time.In, it panics if loc is nil.
+func (fc *fakeClock) In(loc *time.Location) FakeClock {
+	if loc == nil {
+		panic("clockwork: In called with a nil location")
+	}
+	return &locatedClock{FakeClock: fc, loc: loc}
+}
+
+func (lc *locatedClock) Now() time.Time {
+	return lc.FakeClock.Now().In(lc.loc)
+}
+
+func (lc *locatedClock) Since(t time.Time) time.Duration {
+	return lc.Now().Sub(t)
+}
+
+func (lc *locatedClock) Until(t time.Time) time.Duration {
+	return t.Sub(lc.Now())
+}
+
+func (lc *locatedClock) Location() *time.Location {
+	return lc.loc
+}
+
+func (lc *locatedClock) Parse(layout, value string) (time.Time, error) {
+	return time.ParseInLocation(layout, value, lc.loc)
+}
+
+func (lc *locatedClock) Capabilities() ClockCapabilities {
+	c := lc.FakeClock.Capabilities()
+	c.Location = lc.loc
+	return c
+}
+
+// In returns another view of the viewed clock, in loc.
+func (lc *locatedClock) In(loc *time.Location) FakeClock {
+	return lc.FakeClock.In(loc)
+}
@@ -0,0 +1,45 @@
+package clockwork
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClockIn(t *testing.T) {
+	loc := time.FixedZone("east", 9*3600)
+	fc := NewFakeClock()
+	view := fc.In(loc)
+	if now := view.Now(); !now.Equal(fc.Now()) || now.Location() != loc {
+		t.Fatalf("got %v, want %v in %v", now, fc.Now(), loc)
+	}
+	if view.Location() != loc || fc.Location() != time.UTC {
+		t.Errorf("got locations %v and %v, want %v and UTC", view.Location(), fc.Location(), loc)
+	}
+
+	// timers created on either clock fire when advancing the other
+	viewTimer := view.NewTimer(time.Second)
+	timer := fc.NewTimer(2 * time.Second)
+	fc.Advance(time.Second)
+	select {
+	case <-viewTimer.C():
+	default:
+		t.Error("timer of the view did not fire when advancing the clock")
+	}
+	view.Advance(time.Second)
+	select {
+	case <-timer.C():
+	default:
+		t.Error("timer of the clock did not fire when advancing the view")
+	}
+	if !view.Now().Equal(fc.Now()) {
+		t.Errorf("view at %v, clock at %v", view.Now(), fc.Now())
+	}
+	if got := view.Since(fc.Now().Add(-time.Minute)); got != time.Minute {
+		t.Errorf("got Since %v, want %v", got, time.Minute)
+	}
+
+	other := view.In(time.UTC)
+	if other.Now().Location() != time.UTC {
+		t.Errorf("got %v, want a time in UTC", other.Now().Location())
+	}
+}
@@ -0,0 +1,65 @@
+package clockwork
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// OffsetClock is a Clock reporting the time of a base clock shifted by an
+// offset, to simulate clock skew between hosts.
+type OffsetClock interface {
+	Clock
+	// SetOffset changes the offset from the base clock.
+	SetOffset(offset time.Duration)
+}
+
+// offsetClock shifts the time of its base clock by offset.
+type offsetClock struct {
+	Clock        // the base clock, running the timers, sleeps and tickers
+	offset int64 // a time.Duration, accessed atomically
+}
+
+// NewOffsetClock returns a clock whose Now is the time of base shifted by
+// offset. Only Now, Since and Until are shifted, along with the deadline of the
+// contexts of TimerContext and the time their timers send, so that deadlines
+// compare with Now. The rest is that of base: its timers, sleeps and tickers,
+// which send the time of base, and the windows of TruncateToWindow, aligned
+// on the origin of base. Several offset clocks sharing one FakeClock thus
+// report skewed times while all moving with it.
+func NewOffsetClock(base Clock, offset time.Duration) OffsetClock {
+	return &offsetClock{Clock: base, offset: int64(offset)}
+}
+
+// SetOffset changes the offset from the base clock, for instance to simulate a
+// drift correction. It is safe to call concurrently with the other methods.
+func (oc *offsetClock) SetOffset(offset time.Duration) {
+	atomic.StoreInt64(&oc.offset, int64(offset))
+}
+
+func (oc *offsetClock) Now() time.Time {
+	return oc.Clock.Now().Add(time.Duration(atomic.LoadInt64(&oc.offset)))
+}
+
+func (oc *offsetClock) Since(t time.Time) time.Duration {
+	return oc.Now().Sub(t)
+}
+
+func (oc *offsetClock) Until(t time.Time) time.Duration {
+	return t.Sub(oc.Now())
+}
+
+// TimerContext is like the TimerContext of base, with a deadline shifted by
+// the offset like Now.
+func (oc *offsetClock) TimerContext(parent context.Context, d time.Duration) (Timer, context.Context, context.CancelFunc) {
+	return timerContext(oc, oc.Clock.AfterFunc, parent, d)
+}
+
+// Capabilities describes the base clock, except that the offsetClock is not a
+// FakeClock, even over one.
+func (oc *offsetClock) Capabilities() ClockCapabilities {
+	c := oc.Clock.Capabilities()
+	c.Fake = false
+	c.Features &^= FeatureAdvance
+	return c
+}
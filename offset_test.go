@@ -0,0 +1,65 @@
+package clockwork
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestOffsetClock(t *testing.T) {
+	fc := NewFakeClock()
+	oc := NewOffsetClock(fc, time.Minute)
+	if want := fc.Now().Add(time.Minute); !oc.Now().Equal(want) {
+		t.Errorf("got %v, want %v", oc.Now(), want)
+	}
+	if got := oc.Since(fc.Now()); got != time.Minute {
+		t.Errorf("got Since %v, want %v", got, time.Minute)
+	}
+	if got := oc.Until(fc.Now()); got != -time.Minute {
+		t.Errorf("got Until %v, want %v", got, -time.Minute)
+	}
+
+	timer := oc.NewTimer(time.Second)
+	fc.Advance(time.Second)
+	select {
+	case <-timer.C():
+	default:
+		t.Error("timer did not fire with the base clock")
+	}
+
+	oc.SetOffset(-time.Second)
+	if want := fc.Now().Add(-time.Second); !oc.Now().Equal(want) {
+		t.Errorf("got %v after SetOffset, want %v", oc.Now(), want)
+	}
+}
+
+func TestOffsetClockTimerContext(t *testing.T) {
+	fc := NewFakeClock()
+	oc := NewOffsetClock(fc, time.Minute)
+	timer, ctx, cancel := oc.TimerContext(context.Background(), time.Second)
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	if want := oc.Now().Add(time.Second); !ok || !deadline.Equal(want) {
+		t.Errorf("got deadline %v, want %v", deadline, want)
+	}
+	fc.Advance(time.Second)
+	select {
+	case fired := <-timer.C():
+		if !fired.Equal(deadline) {
+			t.Errorf("timer sent %v, want the deadline %v", fired, deadline)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timer did not fire with the base clock")
+	}
+	<-ctx.Done()
+}
+
+func TestOffsetClockCapabilities(t *testing.T) {
+	caps := NewOffsetClock(NewFakeClock(), time.Minute).Capabilities()
+	if caps.Fake || caps.Features.Has(FeatureAdvance) {
+		t.Errorf("offset clock over a fake reports being fake: %+v", caps)
+	}
+	if !caps.Features.Has(FeatureTickerStats) {
+		t.Errorf("offset clock lost the features of its base: %+v", caps)
+	}
+}
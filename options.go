@@ -0,0 +1,46 @@
+package clockwork
+
+import (
+	"time"
+)
+
+// Option configures a FakeClock created by NewFakeClock.
+type Option func(*fakeClockOptions)
+
+type fakeClockOptions struct {
+	start              time.Time
+	loc                *time.Location
+	maxTicksPerAdvance int
+	autoAdvance        time.Duration
+}
+
+// WithStartTime sets the initial time of the FakeClock.
+func WithStartTime(t time.Time) Option {
+	return func(o *fakeClockOptions) {
+		o.start = t
+	}
+}
+
+// WithLocation makes the FakeClock report its time in the given location,
+// regardless of the location of the start time.
+func WithLocation(loc *time.Location) Option {
+	return func(o *fakeClockOptions) {
+		o.loc = loc
+	}
+}
+
+// WithMaxTicksPerAdvance is the construction time equivalent of
+// FakeClock.SetMaxTicksPerAdvance.
+func WithMaxTicksPerAdvance(n int) Option {
+	return func(o *fakeClockOptions) {
+		o.maxTicksPerAdvance = n
+	}
+}
+
+// WithAutoAdvance is the construction time equivalent of
+// FakeClock.SetAutoAdvance.
+func WithAutoAdvance(step time.Duration) Option {
+	return func(o *fakeClockOptions) {
+		o.autoAdvance = step
+	}
+}
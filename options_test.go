@@ -0,0 +1,43 @@
+package clockwork
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewFakeClockOptions(t *testing.T) {
+	start := time.Date(2020, time.January, 16, 23, 12, 12, 0, time.UTC)
+	loc := time.FixedZone("local", -3600)
+
+	fc := NewFakeClock(WithStartTime(start))
+	if now := fc.Now(); !now.Equal(start) || now.Location() != time.UTC {
+		t.Errorf("WithStartTime: got %v, want %v", now, start)
+	}
+
+	fc = NewFakeClock(WithLocation(loc))
+	if now := fc.Now(); now.Location() != loc || now.IsZero() {
+		t.Errorf("WithLocation: got %v in %v, want a non-zero time in %v", now, now.Location(), loc)
+	}
+
+	// The location applies whatever the order of the options.
+	for _, opts := range [][]Option{
+		{WithStartTime(start), WithLocation(loc)},
+		{WithLocation(loc), WithStartTime(start)},
+	} {
+		fc = NewFakeClock(opts...)
+		if now := fc.Now(); !now.Equal(start) || fc.Location() != loc {
+			t.Errorf("got %v in %v, want %v in %v", now, fc.Location(), start, loc)
+		}
+	}
+
+	fc = NewFakeClock(WithStartTime(start), WithMaxTicksPerAdvance(5))
+	if max := fc.(*fakeClock).maxTicks(); max != 5 {
+		t.Errorf("WithMaxTicksPerAdvance: got %d, want %d", max, 5)
+	}
+
+	fc = NewFakeClock(WithStartTime(start), WithAutoAdvance(time.Minute))
+	fc.Sleep(time.Second)
+	if now, want := fc.Now(), start.Add(time.Second); !now.Equal(want) {
+		t.Errorf("WithAutoAdvance: got %v, want %v", now, want)
+	}
+}
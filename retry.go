@@ -0,0 +1,45 @@
+package clockwork
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrExhausted is returned by ScheduledRetrier.Next once every retry of the
+// schedule has been used.
+var ErrExhausted = errors.New("clockwork: retry schedule exhausted")
+
+// ScheduledRetrier paces retries following a fixed schedule, such as retrying
+// after 1s, then 5s, then 30s before giving up. A ScheduledRetrier is not safe
+// for concurrent use.
+type ScheduledRetrier struct {
+	clock    Clock
+	schedule []time.Duration
+	attempt  int
+}
+
+// NewScheduledRetrier returns a ScheduledRetrier waiting on clock for each
+// interval of schedule in turn. The schedule is copied.
+func NewScheduledRetrier(clock Clock, schedule []time.Duration) *ScheduledRetrier {
+	return &ScheduledRetrier{
+		clock:    clock,
+		schedule: append([]time.Duration(nil), schedule...),
+	}
+}
+
+// Next waits for the next interval of the schedule to elapse on the clock and
+// returns the number of the retry which may then be made, starting at 1. Once
+// the schedule is exhausted, it returns ErrExhausted right away. If the
+// context is done first, Next returns the context's error and the interval is
+// waited for again by the next call.
+func (r *ScheduledRetrier) Next(ctx context.Context) (attempt int, err error) {
+	if r.attempt >= len(r.schedule) {
+		return 0, ErrExhausted
+	}
+	if err := r.clock.SleepContext(ctx, r.schedule[r.attempt]); err != nil {
+		return 0, err
+	}
+	r.attempt++
+	return r.attempt, nil
+}
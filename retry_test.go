@@ -0,0 +1,71 @@
+package clockwork
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestScheduledRetrier(t *testing.T) {
+	fc := NewFakeClock()
+	schedule := []time.Duration{time.Second, 5 * time.Second, 30 * time.Second}
+	r := NewScheduledRetrier(fc, schedule)
+
+	type result struct {
+		attempt int
+		err     error
+	}
+	for i, d := range schedule {
+		results := make(chan result)
+		go func() {
+			attempt, err := r.Next(context.Background())
+			results <- result{attempt, err}
+		}()
+		fc.BlockUntil(1)
+		fc.Advance(d - time.Millisecond)
+		select {
+		case res := <-results:
+			t.Fatalf("retry %d came early: %v", i+1, res)
+		case <-time.After(10 * time.Millisecond):
+		}
+		fc.Advance(time.Millisecond)
+		select {
+		case res := <-results:
+			if res.err != nil || res.attempt != i+1 {
+				t.Errorf("got attempt %d (%v), want %d", res.attempt, res.err, i+1)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for retry %d", i+1)
+		}
+	}
+	if attempt, err := r.Next(context.Background()); err != ErrExhausted {
+		t.Errorf("got attempt %d (%v) past the schedule, want %v", attempt, err, ErrExhausted)
+	}
+}
+
+func TestScheduledRetrierContext(t *testing.T) {
+	fc := NewFakeClock()
+	r := NewScheduledRetrier(fc, []time.Duration{time.Second})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if attempt, err := r.Next(ctx); err != context.Canceled {
+		t.Fatalf("got attempt %d (%v), want %v", attempt, err, context.Canceled)
+	}
+
+	// The canceled wait does not use up the retry.
+	done := make(chan int)
+	go func() {
+		attempt, _ := r.Next(context.Background())
+		done <- attempt
+	}()
+	fc.BlockUntil(1)
+	fc.Advance(time.Second)
+	select {
+	case attempt := <-done:
+		if attempt != 1 {
+			t.Errorf("got attempt %d, want %d", attempt, 1)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the retry")
+	}
+}
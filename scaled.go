@@ -0,0 +1,180 @@
+package clockwork
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// scaledClock is a Clock running factor times faster than its base clock.
+type scaledClock struct {
+	Clock  // the base clock, for what needs no scaling
+	factor float64
+	// start is the time of the base clock when the scaledClock was created,
+	// the time from which it runs faster
+	start time.Time
+}
+
+// NewScaledClock returns a Clock running factor times faster than base, for
+// load and soak tests where code waiting for an hour should be done in
+// minutes while still running on real goroutine scheduling. Its time starts at
+// the time of base, then moves by factor times the time elapsed on base. The
+// durations of its sleeps, timers and tickers are divided by factor before
+// being handed to base, so a ticker ticks factor times more often. A factor of
+// 1 returns base itself; a factor which isn't positive panics.
+func NewScaledClock(base Clock, factor float64) Clock {
+	if factor <= 0 {
+		panic(fmt.Sprintf("clockwork: NewScaledClock called with a non-positive factor %v", factor))
+	}
+	if factor == 1 {
+		return base
+	}
+	return &scaledClock{Clock: base, factor: factor, start: base.Now()}
+}
+
+// scale returns the duration on the base clock matching d on sc.
+func (sc *scaledClock) scale(d time.Duration) time.Duration {
+	return time.Duration(float64(d) / sc.factor)
+}
+
+func (sc *scaledClock) Now() time.Time {
+	elapsed := sc.Clock.Now().Sub(sc.start)
+	return sc.start.Add(time.Duration(float64(elapsed) * sc.factor))
+}
+
+func (sc *scaledClock) Since(t time.Time) time.Duration {
+	return sc.Now().Sub(t)
+}
+
+func (sc *scaledClock) Until(t time.Time) time.Duration {
+	return t.Sub(sc.Now())
+}
+
+func (sc *scaledClock) TruncateToWindow(t time.Time, window time.Duration) time.Time {
+	return truncateToWindow(sc.start, t, window)
+}
+
+// Capabilities describes the base clock, except that the scaledClock is not a
+// FakeClock, even over one, and its tickers track their period and dropped
+// ticks.
+func (sc *scaledClock) Capabilities() ClockCapabilities {
+	c := sc.Clock.Capabilities()
+	c.Fake = false
+	c.Features = c.Features&^FeatureAdvance | FeatureTickerStats
+	return c
+}
+
+func (sc *scaledClock) Sleep(d time.Duration) {
+	sc.Clock.Sleep(sc.scale(d))
+}
+
+func (sc *scaledClock) SleepContext(ctx context.Context, d time.Duration) error {
+	return sc.Clock.SleepContext(ctx, sc.scale(d))
+}
+
+func (sc *scaledClock) After(d time.Duration) <-chan time.Time {
+	return sc.NewTimer(d).C()
+}
+
+// NewTimer returns a Timer whose channel receives the time of sc, rather than
+// the time of the base clock, when it fires.
+func (sc *scaledClock) NewTimer(d time.Duration) Timer {
+	t := &scaledTimer{c: make(chan time.Time, 1), sc: sc}
+	t.Timer = sc.Clock.AfterFunc(sc.scale(d), func() {
+		select {
+		case t.c <- sc.Now():
+		default:
+		}
+	})
+	return t
+}
+
+func (sc *scaledClock) AfterFunc(d time.Duration, f func()) Timer {
+	return &scaledTimer{Timer: sc.Clock.AfterFunc(sc.scale(d), f), sc: sc}
+}
+
+func (sc *scaledClock) NewTimerContext(ctx context.Context, d time.Duration) Timer {
+	return &scaledTimer{Timer: sc.Clock.NewTimerContext(ctx, sc.scale(d)), sc: sc}
+}
+
+func (sc *scaledClock) TimerContext(parent context.Context, d time.Duration) (Timer, context.Context, context.CancelFunc) {
+	return timerContext(sc, sc.AfterFunc, parent, d)
+}
+
+func (sc *scaledClock) AtLeast(d time.Duration, f func()) {
+	atLeast(sc, d, f)
+}
+
+// NewTicker returns a Ticker whose channel receives the time of sc, rather
+// than the time of the base clock, on every tick.
+func (sc *scaledClock) NewTicker(d time.Duration) Ticker {
+	t := &scaledTicker{c: make(chan time.Time, 1), period: d}
+	t.Ticker = sc.Clock.NewTickerFunc(sc.scale(d), func() {
+		select {
+		case t.c <- sc.Now():
+		default:
+			atomic.AddUint64(&t.dropped, 1)
+		}
+	})
+	return t
+}
+
+func (sc *scaledClock) Tick(d time.Duration) <-chan time.Time {
+	if d <= 0 {
+		return nil
+	}
+	return sc.NewTicker(d).Chan()
+}
+
+func (sc *scaledClock) NewTickerFunc(d time.Duration, f func()) Ticker {
+	return &scaledTicker{Ticker: sc.Clock.NewTickerFunc(sc.scale(d), f), period: d}
+}
+
+// scaledTimer is a timer of a scaledClock, running on the base clock.
+type scaledTimer struct {
+	Timer
+	// c replaces the channel of Timer when set
+	c  chan time.Time
+	sc *scaledClock
+}
+
+func (t *scaledTimer) C() <-chan time.Time {
+	if t.c != nil {
+		return t.c
+	}
+	return t.Timer.C()
+}
+
+func (t *scaledTimer) Reset(d time.Duration) bool {
+	return t.Timer.Reset(t.sc.scale(d))
+}
+
+// scaledTicker is a ticker of a scaledClock, running on the base clock.
+type scaledTicker struct {
+	Ticker
+	// c replaces the channel of Ticker when set
+	c      chan time.Time
+	period time.Duration
+	// dropped counts the ticks which found c full, atomically
+	dropped uint64
+}
+
+func (t *scaledTicker) Chan() <-chan time.Time {
+	if t.c != nil {
+		return t.c
+	}
+	return t.Ticker.Chan()
+}
+
+func (t *scaledTicker) Dropped() int {
+	if t.c != nil {
+		return int(atomic.LoadUint64(&t.dropped))
+	}
+	return t.Ticker.Dropped()
+}
+
+// Period returns the period of the ticker on the scaledClock.
+func (t *scaledTicker) Period() time.Duration {
+	return t.period
+}
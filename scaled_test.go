@@ -0,0 +1,62 @@
+package clockwork
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScaledClock(t *testing.T) {
+	fc := NewFakeClock()
+	sc := NewScaledClock(fc, 10)
+	start := sc.Now()
+	if !start.Equal(fc.Now()) {
+		t.Fatalf("got start %v, want %v", start, fc.Now())
+	}
+
+	timer := sc.NewTimer(10 * time.Second)
+	ticker := sc.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	if p := ticker.Period(); p != 5*time.Second {
+		t.Errorf("got period %v, want %v", p, 5*time.Second)
+	}
+	fc.BlockUntil(2)
+	fc.Advance(500 * time.Millisecond)
+	if got := sc.Since(start); got != 5*time.Second {
+		t.Errorf("got %v elapsed, want %v", got, 5*time.Second)
+	}
+	select {
+	case tick := <-ticker.Chan():
+		if !tick.Equal(start.Add(5 * time.Second)) {
+			t.Errorf("got tick %v, want %v", tick, start.Add(5*time.Second))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the tick")
+	}
+	select {
+	case <-timer.C():
+		t.Fatal("timer fired early")
+	default:
+	}
+	fc.Advance(500 * time.Millisecond)
+	select {
+	case got := <-timer.C():
+		if want := start.Add(10 * time.Second); !got.Equal(want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the timer")
+	}
+}
+
+func TestScaledClockFactors(t *testing.T) {
+	fc := NewFakeClock()
+	if sc := NewScaledClock(fc, 1); sc != fc {
+		t.Error("a factor of 1 did not return the base clock")
+	}
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expected a panic with a zero factor")
+		}
+	}()
+	NewScaledClock(fc, 0)
+}
@@ -0,0 +1,67 @@
+package clockwork
+
+import (
+	"sync"
+	"time"
+)
+
+// Stopwatch measures the time elapsed on a Clock, possibly across several
+// intervals when it is stopped and started again. With a FakeClock, the
+// measurements are deterministic.
+type Stopwatch struct {
+	clock Clock
+
+	mu      sync.Mutex
+	running bool
+	start   time.Time     // start of the current interval, when running
+	elapsed time.Duration // total of the previous intervals
+}
+
+// NewStopwatch returns a Stopwatch measuring the time elapsed on clock from
+// now on.
+func NewStopwatch(clock Clock) *Stopwatch {
+	return &Stopwatch{clock: clock, running: true, start: clock.Now()}
+}
+
+// Elapsed returns the total time the Stopwatch has been running since it was
+// created or last reset.
+func (s *Stopwatch) Elapsed() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.running {
+		return s.elapsed
+	}
+	return s.elapsed + s.clock.Now().Sub(s.start)
+}
+
+// Stop pauses the Stopwatch: the time elapsing until the next Start is not
+// counted. Stopping a stopped Stopwatch does nothing.
+func (s *Stopwatch) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		s.elapsed += s.clock.Now().Sub(s.start)
+		s.running = false
+	}
+}
+
+// Start resumes a stopped Stopwatch. Starting a running Stopwatch does
+// nothing.
+func (s *Stopwatch) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.running {
+		s.start = s.clock.Now()
+		s.running = true
+	}
+}
+
+// Reset discards the elapsed time and runs the Stopwatch from now on, whether
+// it was stopped or not.
+func (s *Stopwatch) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.elapsed = 0
+	s.start = s.clock.Now()
+	s.running = true
+}
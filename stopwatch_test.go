@@ -0,0 +1,35 @@
+package clockwork
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStopwatch(t *testing.T) {
+	fc := NewFakeClock()
+	s := NewStopwatch(fc)
+	fc.Advance(time.Second)
+	if got := s.Elapsed(); got != time.Second {
+		t.Errorf("got %v elapsed, want %v", got, time.Second)
+	}
+
+	s.Stop()
+	s.Stop()
+	fc.Advance(time.Minute)
+	if got := s.Elapsed(); got != time.Second {
+		t.Errorf("got %v elapsed while stopped, want %v", got, time.Second)
+	}
+	s.Start()
+	s.Start()
+	fc.Advance(2 * time.Second)
+	if got := s.Elapsed(); got != 3*time.Second {
+		t.Errorf("got %v elapsed after restarting, want %v", got, 3*time.Second)
+	}
+
+	s.Stop()
+	s.Reset()
+	fc.Advance(time.Second)
+	if got := s.Elapsed(); got != time.Second {
+		t.Errorf("got %v elapsed after a reset, want %v", got, time.Second)
+	}
+}
@@ -0,0 +1,39 @@
+package clockwork
+
+import (
+	"sync"
+	"time"
+)
+
+// Throttle lets an action happen at most once per interval of a Clock, for
+// instance to keep a repeated log message from spamming. Unlike a rate
+// limiter, it never allows bursts.
+type Throttle struct {
+	clock    Clock
+	interval time.Duration
+
+	mu      sync.Mutex
+	allowed bool
+	last    time.Time
+}
+
+// NewThrottle returns a Throttle allowing an action once per interval of
+// clock.
+func NewThrottle(clock Clock, interval time.Duration) *Throttle {
+	return &Throttle{clock: clock, interval: interval}
+}
+
+// Allow reports whether the action may happen now: the first call returns
+// true, then Allow returns false until interval has elapsed on the clock since
+// the last call which returned true.
+func (t *Throttle) Allow() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.clock.Now()
+	if t.allowed && now.Sub(t.last) < t.interval {
+		return false
+	}
+	t.allowed = true
+	t.last = now
+	return true
+}
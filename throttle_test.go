@@ -0,0 +1,36 @@
+package clockwork
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThrottle(t *testing.T) {
+	fc := NewFakeClock()
+	th := NewThrottle(fc, time.Minute)
+	if !th.Allow() {
+		t.Fatal("the first call was not allowed")
+	}
+	for i := 0; i < 3; i++ {
+		if th.Allow() {
+			t.Fatal("allowed twice in the same interval")
+		}
+	}
+	fc.Advance(59 * time.Second)
+	if th.Allow() {
+		t.Fatal("allowed before the interval elapsed")
+	}
+	fc.Advance(time.Second)
+	if !th.Allow() {
+		t.Fatal("not allowed once the interval elapsed")
+	}
+	// The interval starts over from the last allowed call.
+	fc.Advance(30 * time.Second)
+	if th.Allow() {
+		t.Fatal("allowed before the interval elapsed again")
+	}
+	fc.Advance(time.Hour)
+	if !th.Allow() || th.Allow() {
+		t.Fatal("a long pause allowed more than one call")
+	}
+}
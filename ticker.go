@@ -1,6 +1,9 @@
 package clockwork
 
 import (
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -11,6 +14,12 @@ import (
 type Ticker interface {
 	Chan() <-chan time.Time
 	Stop()
+	// Period returns the duration between two ticks; it is zero for tickers
+	// of the real clock, whose period is not tracked
+	Period() time.Duration
+	// Dropped returns how many ticks could not be delivered because the
+	// channel was full; it is zero for tickers of the real clock
+	Dropped() int
 }
 
 type realTicker struct{ *time.Ticker }
@@ -19,19 +28,86 @@ func (rt *realTicker) Chan() <-chan time.Time {
 	return rt.C
 }
 
+// Period is not supported by real tickers and returns zero.
+func (rt *realTicker) Period() time.Duration {
+	return 0
+}
+
+// Dropped is not supported by real tickers and returns zero.
+func (rt *realTicker) Dropped() int {
+	return 0
+}
+
+// realTickerFunc is a real ticker calling a function on every tick.
+type realTickerFunc struct {
+	realTicker
+	stop      chan struct{}
+	closeStop sync.Once
+}
+
+func (rt *realTickerFunc) Chan() <-chan time.Time {
+	return nil
+}
+
+func (rt *realTickerFunc) Stop() {
+	rt.Ticker.Stop()
+	rt.closeStop.Do(func() { close(rt.stop) })
+}
+
 type fakeTicker struct {
-	c      chan time.Time
-	stop   chan bool
-	clock  FakeClock
-	period time.Duration
+	c         chan time.Time
+	stop      chan struct{}
+	closeStop sync.Once
+	clock     *fakeClock
+	period    time.Duration
+	stopped   uint32
+	// mu guards next, the sleeper of the pending tick, so that Stop can
+	// remove it from the clock
+	mu   sync.Mutex
+	next *sleeper
+	// dropped counts the ticks which found c full, atomically
+	dropped uint64
+	// fn is called on every tick instead of sending on c, see NewTickerFunc
+	fn func()
 }
 
 func (ft *fakeTicker) Chan() <-chan time.Time {
 	return ft.c
 }
 
+func (ft *fakeTicker) Period() time.Duration {
+	return ft.period
+}
+
+// Dropped returns how many ticks found the channel full and were discarded.
+// The ticks coalesced by an advance spanning several periods are not counted,
+// except for a ticker from NewTickerSize, which would have buffered them.
+func (ft *fakeTicker) Dropped() int {
+	return int(atomic.LoadUint64(&ft.dropped))
+}
+
+// Stop stops the ticker and removes its pending tick from the clock. It can
+// be called several times.
 func (ft *fakeTicker) Stop() {
-	ft.stop <- true
+	ft.closeStop.Do(func() {
+		ft.mu.Lock()
+		atomic.StoreUint32(&ft.stopped, 1)
+		if ft.next != nil {
+			ft.next.Stop()
+		}
+		ft.mu.Unlock()
+		close(ft.stop)
+	})
+}
+
+func (ft *fakeTicker) isStopped() bool {
+	return atomic.LoadUint32(&ft.stopped) == 1
+}
+
+// leakCheckedTicker wraps a fakeTicker handed out while leak finalizers are
+// enabled.
+type leakCheckedTicker struct {
+	*fakeTicker
 }
 
 // tick sends the tick time to the ticker channel after every period.
@@ -41,26 +117,61 @@ func (ft *fakeTicker) tick() {
 	tick := ft.clock.Now()
 	for {
 		tick = tick.Add(ft.period)
-		remaining := tick.Sub(ft.clock.Now())
-		if remaining <= 0 {
-			// The tick should have already happened. This can happen when
-			// Advance() is called on the fake clock with a duration larger
-			// than this ticker's period.
+		if remaining := tick.Sub(ft.clock.Now()); remaining > 0 {
+			ft.mu.Lock()
+			if ft.isStopped() {
+				ft.mu.Unlock()
+				return
+			}
+			ft.next = ft.clock.newTimer(remaining, KindTicker)
+			ft.mu.Unlock()
 			select {
-			case ft.c <- tick:
-			default:
+			case <-ft.stop:
+				return
+			case <-ft.next.C():
+			}
+		}
+		// Advance() may have been called on the fake clock with a duration
+		// larger than this ticker's period, making several ticks due at
+		// once. Like time.Ticker, only the most recent one is delivered,
+		// unless the channel was sized to buffer the others.
+		missed := ft.clock.Now().Sub(tick) / ft.period
+		// buffered is how many of the missed ticks may be sent before the
+		// most recent one: with a cap, at most max ticks are delivered.
+		buffered := missed
+		max := ft.clock.maxTicks()
+		capped := max > 0 && int64(missed) >= int64(max)
+		if capped {
+			buffered = time.Duration(max - 1)
+			ft.clock.recordError(fmt.Errorf("clockwork: ticker with period %v exceeded %d ticks in a single advance, it was stopped", ft.period, max))
+		}
+		if cap(ft.c) > 1 {
+			i := time.Duration(0)
+			for ; i < buffered && len(ft.c) < cap(ft.c); i++ {
+				ft.c <- tick.Add(i * ft.period)
 			}
-			continue
+			atomic.AddUint64(&ft.dropped, uint64(buffered-i))
 		}
+		tick = tick.Add(missed * ft.period)
+		ft.send(tick)
+		if capped {
+			ft.Stop()
+			return
+		}
+	}
+}
 
+// send delivers a tick without blocking and lets the clock know that a
+// ticker fired.
+func (ft *fakeTicker) send(tick time.Time) {
+	if ft.fn != nil {
+		go ft.fn()
+	} else {
 		select {
-		case <-ft.stop:
-			return
-		case <-ft.clock.After(remaining):
-			select {
-			case ft.c <- tick:
-			default:
-			}
+		case ft.c <- tick:
+		default:
+			atomic.AddUint64(&ft.dropped, 1)
 		}
 	}
+	ft.clock.notifyTick()
 }
@@ -1,7 +1,12 @@
 package clockwork
 
 import (
+	"context"
+	"runtime"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestFakeTickerStop(t *testing.T) {
@@ -16,14 +21,26 @@ func TestFakeTickerStop(t *testing.T) {
 	}
 }
 
+func TestFakeTickerNonPositivePeriod(t *testing.T) {
+	fc := NewFakeClock()
+	for _, d := range []time.Duration{0, -time.Second} {
+		assert.Panics(t, func() { fc.NewTicker(d) })
+		assert.Panics(t, func() { fc.NewTickerFunc(d, func() {}) })
+		assert.Panics(t, func() { fc.NewTickerSize(d, 2) })
+	}
+	if c := fc.Tick(0); c != nil {
+		t.Error("Tick(0) returned a channel")
+	}
+}
+
 func TestFakeTickerTick(t *testing.T) {
 	fc := &fakeClock{}
 	now := fc.Now()
 
-	// The tick at now.Add(2) should not get through since we advance time by
-	// two units below and the channel can hold at most one tick until it's
-	// consumed.
-	first := now.Add(1)
+	// Only the tick at now.Add(2) should get through since we advance time
+	// by two units below: like time.Ticker, the ticks missed during an
+	// advance are coalesced into the most recent one.
+	first := now.Add(2)
 	second := now.Add(3)
 
 	// We wrap the Advance() calls with blockers to make sure that the ticker
@@ -56,3 +73,299 @@ func TestFakeTickerTick(t *testing.T) {
 	}
 	ft.Stop()
 }
+
+func TestFakeTickerMaxTicksPerAdvance(t *testing.T) {
+	fc := &fakeClock{}
+	fc.SetMaxTicksPerAdvance(3)
+
+	start := fc.Now()
+	ft := fc.NewTickerSize(1, 10)
+	fc.BlockUntil(1)
+	fc.Advance(1000)
+	// The ticker delivers the ticks up to the cap, the last one being the
+	// most recent, then gives up instead of re-arming.
+	for _, want := range []time.Time{start.Add(1), start.Add(2), start.Add(1000)} {
+		select {
+		case tick := <-ft.Chan():
+			if !tick.Equal(want) {
+				t.Errorf("got tick %v, want %v", tick, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected a tick at %v", want)
+		}
+	}
+	// The error is recorded before the ticks are delivered.
+	if n := len(fc.Errors()); n != 1 {
+		t.Errorf("got %d errors, want %d", n, 1)
+	}
+	// The ticker was stopped, so it does not tick again.
+	fc.BlockUntil(0)
+	withTimeout(t, time.Second, func() {
+		for !ft.(*fakeTicker).isStopped() {
+			time.Sleep(time.Millisecond)
+		}
+	})
+	fc.Advance(1)
+	select {
+	case tick := <-ft.Chan():
+		t.Errorf("got tick %v beyond the cap", tick)
+	default:
+	}
+	// Stopping it again is harmless.
+	ft.Stop()
+}
+
+func TestFakeTickerUnderMaxTicksPerAdvance(t *testing.T) {
+	fc := &fakeClock{}
+	fc.SetMaxTicksPerAdvance(3)
+
+	ft := fc.NewTicker(1)
+	fc.BlockUntil(1)
+	fc.Advance(3)
+	fc.BlockUntil(1)
+	if errs := fc.Errors(); len(errs) != 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+	ft.Stop()
+}
+
+func TestFakeClockWaitNextTick(t *testing.T) {
+	fc := &fakeClock{}
+	ft := fc.NewTicker(5)
+	fc.BlockUntil(1)
+
+	waited := make(chan error)
+	go func() {
+		waited <- fc.WaitNextTick(context.Background())
+	}()
+	fc.Advance(4)
+	select {
+	case <-waited:
+		t.Fatalf("WaitNextTick returned before the ticker fired")
+	case <-time.After(10 * time.Millisecond):
+	}
+	fc.Advance(1)
+	select {
+	case err := <-waited:
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for the next tick")
+	}
+	ft.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := fc.WaitNextTick(ctx); err != context.Canceled {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestFakeTickerLeakFinalizers(t *testing.T) {
+	fc := &fakeClock{}
+	fc.EnableLeakFinalizers()
+
+	fc.NewTicker(1).Stop()
+	fc.NewTicker(1) // leaked
+	deadline := time.Now().Add(5 * time.Second)
+	for len(fc.Errors()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("leaked ticker was not reported")
+		}
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+	// Give the stopped ticker's finalizer a chance to run as well.
+	runtime.GC()
+	time.Sleep(10 * time.Millisecond)
+	if n := len(fc.Errors()); n != 1 {
+		t.Errorf("got %d errors, want %d: %v", n, 1, fc.Errors())
+	}
+}
+
+func TestTickerPeriod(t *testing.T) {
+	fc := &fakeClock{}
+	ft := fc.NewTicker(5 * time.Second)
+	defer ft.Stop()
+	if p := ft.Period(); p != 5*time.Second {
+		t.Errorf("got period %v, want %v", p, 5*time.Second)
+	}
+	rt := NewRealClock().NewTicker(time.Hour)
+	defer rt.Stop()
+	if p := rt.Period(); p != 0 {
+		t.Errorf("got period %v for a real ticker, want 0", p)
+	}
+}
+
+func TestFakeTickerCoalescesMissedTicks(t *testing.T) {
+	fc := NewFakeClock()
+	start := fc.Now()
+	ft := fc.NewTicker(time.Second)
+	defer ft.Stop()
+	fc.BlockUntil(1)
+	fc.Advance(5500 * time.Millisecond)
+	fc.BlockUntil(1)
+	select {
+	case tick := <-ft.Chan():
+		if want := start.Add(5 * time.Second); !tick.Equal(want) {
+			t.Errorf("got tick %v, want the latest boundary %v", tick, want)
+		}
+	default:
+		t.Fatal("expected a tick")
+	}
+	select {
+	case tick := <-ft.Chan():
+		t.Fatalf("got a second tick %v", tick)
+	default:
+	}
+
+	// The ticker keeps its phase.
+	fc.Advance(500 * time.Millisecond)
+	fc.BlockUntil(1)
+	select {
+	case tick := <-ft.Chan():
+		if want := start.Add(6 * time.Second); !tick.Equal(want) {
+			t.Errorf("got tick %v, want %v", tick, want)
+		}
+	default:
+		t.Fatal("expected a tick")
+	}
+}
+
+func TestFakeTickerFunc(t *testing.T) {
+	fc := NewFakeClock()
+	calls := make(chan struct{}, 10)
+	ft := fc.NewTickerFunc(time.Second, func() { calls <- struct{}{} })
+	if ft.Chan() != nil {
+		t.Error("a TickerFunc has a channel")
+	}
+	for i := 0; i < 3; i++ {
+		fc.BlockUntil(1)
+		fc.Advance(time.Second)
+		select {
+		case <-calls:
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for call %d", i+1)
+		}
+	}
+	ft.Stop()
+	fc.Advance(time.Minute)
+	select {
+	case <-calls:
+		t.Error("f called after the ticker stopped")
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestRealTickerFunc(t *testing.T) {
+	calls := make(chan struct{}, 10)
+	rt := NewRealClock().NewTickerFunc(time.Millisecond, func() {
+		select {
+		case calls <- struct{}{}:
+		default:
+		}
+	})
+	for i := 0; i < 2; i++ {
+		select {
+		case <-calls:
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for call %d", i+1)
+		}
+	}
+	rt.Stop()
+	rt.Stop()
+}
+
+func TestFakeClockTick(t *testing.T) {
+	fc := NewFakeClock()
+	if c := fc.Tick(0); c != nil {
+		t.Error("got a channel for a zero period")
+	}
+	start := fc.Now()
+	c := fc.Tick(time.Second)
+	fc.BlockUntil(1)
+	fc.Advance(time.Second)
+	select {
+	case got := <-c:
+		if want := start.Add(time.Second); !got.Equal(want) {
+			t.Errorf("got tick %v, want %v", got, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the tick")
+	}
+}
+
+func TestFakeTickerSize(t *testing.T) {
+	fc := NewFakeClock()
+	start := fc.Now()
+	ft := fc.NewTickerSize(time.Second, 4)
+	defer ft.Stop()
+	fc.BlockUntil(1)
+	fc.Advance(3 * time.Second)
+	fc.BlockUntil(1)
+	for i := 1; i <= 3; i++ {
+		select {
+		case got := <-ft.Chan():
+			if want := start.Add(time.Duration(i) * time.Second); !got.Equal(want) {
+				t.Errorf("got tick %d at %v, want %v", i, got, want)
+			}
+		default:
+			t.Fatalf("tick %d was not buffered", i)
+		}
+	}
+
+	// the ticks beyond the buffer are dropped
+	fc.Advance(10 * time.Second)
+	fc.BlockUntil(1)
+	if n := len(ft.Chan()); n != 4 {
+		t.Errorf("got %d ticks buffered, want 4", n)
+	}
+}
+
+func TestFakeTickerDropped(t *testing.T) {
+	fc := NewFakeClock()
+	ft := fc.NewTicker(time.Second)
+	defer ft.Stop()
+	for i := 0; i < 3; i++ {
+		fc.BlockUntil(1)
+		fc.Advance(time.Second)
+	}
+	fc.BlockUntil(1)
+	if got := ft.Dropped(); got != 2 {
+		t.Errorf("got %d dropped ticks, want 2", got)
+	}
+	<-ft.Chan()
+	fc.Advance(5 * time.Second)
+	fc.BlockUntil(1)
+	if got := ft.Dropped(); got != 2 {
+		t.Errorf("got %d dropped ticks after coalescing, want 2", got)
+	}
+
+	sized := fc.NewTickerSize(time.Second, 2)
+	defer sized.Stop()
+	fc.BlockUntil(2)
+	fc.Advance(5 * time.Second)
+	fc.BlockUntil(2)
+	if got := sized.Dropped(); got != 3 {
+		t.Errorf("got %d dropped ticks for a sized ticker, want 3", got)
+	}
+	if got := NewRealClock().NewTicker(time.Hour).Dropped(); got != 0 {
+		t.Errorf("got %d dropped ticks for a real ticker", got)
+	}
+}
+
+func BenchmarkFakeTickerReinsert(b *testing.B) {
+	const waiters = 10000
+	fc := newFakeClockWithWaiters(waiters)
+	ft := fc.NewTicker(time.Second)
+	defer ft.Stop()
+	fc.BlockUntil(waiters + 1)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fc.Advance(time.Second)
+		<-ft.Chan()
+		fc.BlockUntil(waiters + 1)
+	}
+}
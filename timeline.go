@@ -0,0 +1,115 @@
+package clockwork
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timelineEntry is a sleeper seen relative to the current time of its clock.
+type timelineEntry struct {
+	kind WaiterKind
+	in   time.Duration
+}
+
+// DiffTimeline compares the sleepers pending on fakeClock with the ones
+// pending on other, for instance to check the current code schedules the same
+// work as a known-good baseline. Expirations are compared relative to the
+// current time of each clock, so the clocks need not be at the same time. It
+// returns one line per difference, an empty slice when the timelines match:
+// sleepers of a kind expiring at different times on the two clocks are paired
+// up in order of expiration, and the sleepers left over are reported as
+// missing from one of the clocks.
+func (fc *fakeClock) DiffTimeline(other FakeClock) []string {
+	mine, theirs := timelineEntries(fc), timelineEntries(other)
+	// Drop the sleepers both clocks agree on.
+	var onlyMine, onlyTheirs []timelineEntry
+	i, j := 0, 0
+	for i < len(mine) && j < len(theirs) {
+		switch {
+		case mine[i] == theirs[j]:
+			i++
+			j++
+		case mine[i].less(theirs[j]):
+			onlyMine = append(onlyMine, mine[i])
+			i++
+		default:
+			onlyTheirs = append(onlyTheirs, theirs[j])
+			j++
+		}
+	}
+	onlyMine = append(onlyMine, mine[i:]...)
+	onlyTheirs = append(onlyTheirs, theirs[j:]...)
+
+	diff := []string{}
+	for _, w := range onlyMine {
+		k := -1
+		for idx, o := range onlyTheirs {
+			if o.kind == w.kind {
+				k = idx
+				break
+			}
+		}
+		if k < 0 {
+			diff = append(diff, fmt.Sprintf("%s expiring in %v is missing from the other clock", w.kind, w.in))
+			continue
+		}
+		diff = append(diff, fmt.Sprintf("%s expires in %v, in %v on the other clock", w.kind, w.in, onlyTheirs[k].in))
+		onlyTheirs = append(onlyTheirs[:k:k], onlyTheirs[k+1:]...)
+	}
+	for _, o := range onlyTheirs {
+		diff = append(diff, fmt.Sprintf("%s expiring in %v is only on the other clock", o.kind, o.in))
+	}
+	return diff
+}
+
+func (w timelineEntry) less(o timelineEntry) bool {
+	if w.in != o.in {
+		return w.in < o.in
+	}
+	return w.kind < o.kind
+}
+
+// timelineEntries returns the sleepers of fc sorted by expiration then kind.
+func timelineEntries(fc FakeClock) []timelineEntry {
+	now := fc.Now()
+	infos := fc.Waiters()
+	waiters := make([]timelineEntry, len(infos))
+	for i, info := range infos {
+		waiters[i] = timelineEntry{kind: info.Kind, in: info.FireAt.Sub(now)}
+	}
+	sort.Slice(waiters, func(i, j int) bool { return waiters[i].less(waiters[j]) })
+	return waiters
+}
+
+// GraphvizTimeline returns a Graphviz DOT graph of the sleepers pending on
+// fakeClock, for debugging complex schedules: a chain of nodes laid out from
+// left to right, starting with the current time, then one node per sleeper by
+// order of expiration, labeled with its kind, name if any, expiration and
+// time left until then. The output only depends on the state of the clock.
+func (fc *fakeClock) GraphvizTimeline() string {
+	now := fc.Now()
+	waiters := fc.Waiters()
+	var b strings.Builder
+	b.WriteString("digraph timeline {\n\trankdir=LR;\n\tnode [shape=box];\n")
+	fmt.Fprintf(&b, "\tnow [label=%s, shape=ellipse];\n", strconv.Quote("now\n"+now.Format(time.RFC3339Nano)))
+	for i, w := range waiters {
+		label := w.Kind.String()
+		if w.Name != "" {
+			label += " " + w.Name
+		}
+		label += "\n" + w.FireAt.Format(time.RFC3339Nano) + "\n+" + w.FireAt.Sub(now).String()
+		fmt.Fprintf(&b, "\tw%d [label=%s];\n", i, strconv.Quote(label))
+	}
+	if len(waiters) > 0 {
+		b.WriteString("\tnow")
+		for i := range waiters {
+			fmt.Fprintf(&b, " -> w%d", i)
+		}
+		b.WriteString(";\n")
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
@@ -0,0 +1,114 @@
+package clockwork
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFakeClockWaiters(t *testing.T) {
+	fc := NewFakeClock()
+	now := fc.Now()
+	fc.NewTimer(2 * time.Second)
+	fc.AfterFunc(time.Second, func() {})
+	fc.NewTimer(time.Second)
+	want := []WaiterInfo{
+		{Kind: KindAfterFunc, FireAt: now.Add(time.Second)},
+		{Kind: KindTimer, FireAt: now.Add(time.Second)},
+		{Kind: KindTimer, FireAt: now.Add(2 * time.Second)},
+	}
+	if got := fc.Waiters(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestFakeClockPendingWaiters(t *testing.T) {
+	fc := NewFakeClock()
+	now := fc.Now()
+	fc.NewTimer(2 * time.Second)
+	fc.Advance(time.Second)
+	fc.AfterFunc(time.Second, func() {})
+	want := []PendingWaiter{
+		{Kind: KindTimer, FireAt: now.Add(2 * time.Second), Remaining: time.Second},
+		{Kind: KindAfterFunc, FireAt: now.Add(2 * time.Second), Remaining: time.Second},
+	}
+	got := fc.PendingWaiters()
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	fc.Rewind(time.Second)
+	if got := fc.PendingWaiters(); got[0].Remaining != 2*time.Second {
+		t.Errorf("got %v remaining after rewinding, want %v", got[0].Remaining, 2*time.Second)
+	}
+}
+
+func TestFakeClockString(t *testing.T) {
+	fc := NewFakeClock()
+	if got, want := fc.String(), "clock@1984-04-04T00:00:00Z waiters=[]"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	ticker := fc.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	fc.BlockUntil(1)
+	fc.NewTimer(time.Second)
+	want := "clock@1984-04-04T00:00:00Z waiters=[+1s timer, +5s ticker]"
+	if got := fmt.Sprint(fc); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFakeClockDiffTimeline(t *testing.T) {
+	baseline := NewFakeClock()
+	current := NewFakeClockAt(baseline.Now().Add(time.Hour))
+	if diff := current.DiffTimeline(baseline); len(diff) != 0 {
+		t.Fatalf("got differences between empty timelines: %q", diff)
+	}
+
+	for _, fc := range []FakeClock{baseline, current} {
+		fc.NewTimer(time.Second)
+		fc.AfterFunc(time.Minute, func() {})
+	}
+	if diff := current.DiffTimeline(baseline); len(diff) != 0 {
+		t.Fatalf("got differences between matching timelines: %q", diff)
+	}
+
+	baseline.NewTimer(2 * time.Second)
+	current.NewTimer(3 * time.Second)
+	current.AfterFunc(time.Hour, func() {})
+	baseline.NewTimer(time.Hour)
+	want := []string{
+		"timer expires in 3s, in 2s on the other clock",
+		"afterfunc expiring in 1h0m0s is missing from the other clock",
+		"timer expiring in 1h0m0s is only on the other clock",
+	}
+	if diff := current.DiffTimeline(baseline); !reflect.DeepEqual(diff, want) {
+		t.Errorf("got %q, want %q", diff, want)
+	}
+}
+
+func TestFakeClockGraphvizTimeline(t *testing.T) {
+	fc := NewFakeClockAt(time.Date(1999, time.February, 3, 4, 5, 6, 0, time.UTC))
+	want := "digraph timeline {\n" +
+		"\trankdir=LR;\n" +
+		"\tnode [shape=box];\n" +
+		"\tnow [label=\"now\\n1999-02-03T04:05:06Z\", shape=ellipse];\n" +
+		"}\n"
+	if got := fc.GraphvizTimeline(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	fc.AfterFuncNamed(2*time.Second, "retry", func() {})
+	fc.NewTimer(time.Second)
+	want = "digraph timeline {\n" +
+		"\trankdir=LR;\n" +
+		"\tnode [shape=box];\n" +
+		"\tnow [label=\"now\\n1999-02-03T04:05:06Z\", shape=ellipse];\n" +
+		"\tw0 [label=\"timer\\n1999-02-03T04:05:07Z\\n+1s\"];\n" +
+		"\tw1 [label=\"afterfunc retry\\n1999-02-03T04:05:08Z\\n+2s\"];\n" +
+		"\tnow -> w0 -> w1;\n" +
+		"}\n"
+	if got := fc.GraphvizTimeline(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,83 @@
+package clockwork
+
+import (
+	"sync"
+	"time"
+)
+
+// WaitGroup is like sync.WaitGroup, with a wait that may time out according to
+// a Clock. With a FakeClock, WaitTimeout times out only when the clock is
+// advanced past its timeout.
+type WaitGroup struct {
+	clock Clock
+
+	mu sync.Mutex
+	n  int
+	// done is closed when the counter drops to zero
+	done chan struct{}
+}
+
+// NewWaitGroup returns a WaitGroup which times out waits using clock.
+func NewWaitGroup(clock Clock) *WaitGroup {
+	return &WaitGroup{clock: clock}
+}
+
+// Add adds delta, which may be negative, to the counter of the WaitGroup. If
+// the counter drops to zero, all waiters are released. It panics if the
+// counter goes negative.
+func (wg *WaitGroup) Add(delta int) {
+	wg.mu.Lock()
+	defer wg.mu.Unlock()
+	wg.n += delta
+	if wg.n < 0 {
+		panic("clockwork: negative WaitGroup counter")
+	}
+	if wg.n == 0 && wg.done != nil {
+		close(wg.done)
+		wg.done = nil
+	}
+}
+
+// Done decrements the counter of the WaitGroup by one.
+func (wg *WaitGroup) Done() {
+	wg.Add(-1)
+}
+
+// Wait blocks until the counter of the WaitGroup is zero.
+func (wg *WaitGroup) Wait() {
+	if done := wg.doneChan(); done != nil {
+		<-done
+	}
+}
+
+// WaitTimeout blocks until the counter of the WaitGroup is zero, or d has
+// elapsed on the clock. It returns true if the counter reached zero, false if
+// it timed out.
+func (wg *WaitGroup) WaitTimeout(d time.Duration) bool {
+	done := wg.doneChan()
+	if done == nil {
+		return true
+	}
+	timer := wg.clock.NewTimer(d)
+	select {
+	case <-done:
+		timer.Stop()
+		return true
+	case <-timer.C():
+		return false
+	}
+}
+
+// doneChan returns the channel closed when the counter drops to zero, or nil
+// if it is zero already.
+func (wg *WaitGroup) doneChan() chan struct{} {
+	wg.mu.Lock()
+	defer wg.mu.Unlock()
+	if wg.n == 0 {
+		return nil
+	}
+	if wg.done == nil {
+		wg.done = make(chan struct{})
+	}
+	return wg.done
+}
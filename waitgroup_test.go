@@ -0,0 +1,64 @@
+package clockwork
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWaitGroupCompletesInTime(t *testing.T) {
+	fc := NewFakeClock()
+	wg := NewWaitGroup(fc)
+	if !wg.WaitTimeout(time.Second) {
+		t.Fatalf("empty WaitGroup timed out")
+	}
+	wg.Add(2)
+	result := make(chan bool)
+	go func() {
+		result <- wg.WaitTimeout(time.Second)
+	}()
+	fc.BlockUntil(1)
+	wg.Done()
+	fc.Advance(999 * time.Millisecond)
+	wg.Done()
+	select {
+	case ok := <-result:
+		if !ok {
+			t.Errorf("WaitTimeout timed out, want completion")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for WaitTimeout to return")
+	}
+	// The timeout timer was released.
+	fc.BlockUntil(0)
+}
+
+func TestWaitGroupTimesOut(t *testing.T) {
+	fc := NewFakeClock()
+	wg := NewWaitGroup(fc)
+	wg.Add(1)
+	result := make(chan bool)
+	go func() {
+		result <- wg.WaitTimeout(time.Second)
+	}()
+	fc.BlockUntil(1)
+	fc.Advance(time.Second)
+	select {
+	case ok := <-result:
+		if ok {
+			t.Errorf("WaitTimeout completed, want a timeout")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for WaitTimeout to return")
+	}
+	wg.Done()
+	withTimeout(t, time.Second, wg.Wait)
+}
+
+func TestWaitGroupNegativeCounter(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("negative counter didn't panic")
+		}
+	}()
+	NewWaitGroup(NewFakeClock()).Done()
+}